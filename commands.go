@@ -0,0 +1,411 @@
+package main
+
+import (
+	"biathlon-prototype/configs"
+	"biathlon-prototype/events"
+	"biathlon-prototype/models"
+	"biathlon-prototype/race"
+	"biathlon-prototype/validate"
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// command описывает подкоманду командной строки
+type command struct {
+	name        string
+	description string
+	run         func(args []string) int
+}
+
+var commands = []command{
+	{"process", "обработать события, записать логи и вывести результаты", runProcess},
+	{"validate", "проверить файл событий и вывести ошибки с номерами строк", runValidate},
+	{"report", "обработать события и вывести только итоговый отчет", runReport},
+	{"serve", "вести гонку в реальном времени: HTTP API и поток SSE", runServe},
+	{"startlist", "построить стартовый протокол по жеребьевке или провести ее", runStartList},
+	{"pursuit", "стартовый протокол гонки преследования по протоколу предыдущей гонки", runPursuit},
+}
+
+// Поддерживаемые форматы вывода результатов
+var outputFormats = []string{"text", "json", "csv"}
+
+// options содержит общие для всех подкоманд флаги
+type options struct {
+	configPath string
+	rosterPath string
+	eventsPath string
+	outDir     string
+	format     string
+	logFormat  string
+	utc        bool
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "Использование: biathlon <команда> [флаги]")
+	fmt.Fprintln(w, "\nКоманды:")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-10s %s\n", cmd.name, cmd.description)
+	}
+	fmt.Fprintln(w, "\nСправка по флагам команды: biathlon <команда> -h")
+}
+
+// parseOptions разбирает флаги подкоманды. Через extra подкоманда
+// может добавить собственные флаги
+func parseOptions(name string, args []string, extra ...func(fs *flag.FlagSet)) (options, error) {
+	var opts options
+
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	for _, register := range extra {
+		register(fs)
+	}
+	fs.StringVar(&opts.configPath, "config", filepath.Join("input_files", "config.json"), "путь к конфигурации гонки: .json, .yaml, .yml или .toml")
+	fs.StringVar(&opts.rosterPath, "roster", "", "ростер участников: .csv или .json (необязательно)")
+	fs.StringVar(&opts.eventsPath, "events", filepath.Join("input_files", "events.txt"), "путь к файлу событий (- для чтения из stdin)")
+	fs.StringVar(&opts.outDir, "out", "logs", "папка для логов и выходных файлов")
+	fs.StringVar(&opts.format, "format", "text", "формат вывода результатов: "+strings.Join(outputFormats, ", "))
+	fs.StringVar(&opts.logFormat, "log-format", "text", "формат журнала событий: text, raw, json")
+	fs.BoolVar(&opts.utc, "utc", false, "выводить время в журнале и отчетах в UTC, а не в часовом поясе гонки")
+
+	if err := fs.Parse(args); err != nil {
+		return opts, err
+	}
+	// Ошибки разбора флагов уже напечатаны пакетом flag, свои печатаем так же
+	if fs.NArg() > 0 {
+		err := fmt.Errorf("лишние аргументы: %s", strings.Join(fs.Args(), " "))
+		fmt.Fprintln(fs.Output(), err)
+		return opts, err
+	}
+	if !isKnownFormat(opts.format) {
+		err := fmt.Errorf("неизвестный формат вывода %q (доступны: %s)", opts.format, strings.Join(outputFormats, ", "))
+		fmt.Fprintln(fs.Output(), err)
+		return opts, err
+	}
+	if _, err := events.RendererByName(opts.logFormat); err != nil {
+		fmt.Fprintln(fs.Output(), err)
+		return opts, err
+	}
+	return opts, nil
+}
+
+func isKnownFormat(format string) bool {
+	for _, f := range outputFormats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// openEvents открывает файл событий; "-" означает стандартный ввод
+func openEvents(path string) (io.ReadCloser, error) {
+	if path == "-" {
+		return io.NopCloser(os.Stdin), nil
+	}
+	return os.Open(path)
+}
+
+// loadRoster загружает ростер, если он указан; без него возвращает nil
+func loadRoster(opts options) ([]models.Entry, error) {
+	if opts.rosterPath == "" {
+		return nil, nil
+	}
+	roster, err := configs.LoadRoster(opts.rosterPath)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка загрузки ростера: %v", err)
+	}
+	return roster, nil
+}
+
+// loadRace загружает конфигурацию и ростер и создает по ним гонку
+func loadRace(opts options) (*race.Race, error) {
+	cfg, err := configs.LoadConfig(opts.configPath)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка загрузки конфигурации: %v", err)
+	}
+	roster, err := loadRoster(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := race.NewRace(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка создания гонки: %v", err)
+	}
+	if roster != nil {
+		if err := r.SetRoster(roster); err != nil {
+			return nil, fmt.Errorf("ошибка состава команд: %v", err)
+		}
+	} else if r.Format.Name() == race.FormatRelay {
+		return nil, fmt.Errorf("для эстафеты нужен ростер с командами и этапами (-roster)")
+	}
+
+	// Формат журнала уже проверен в parseOptions
+	r.Renderer, _ = events.RendererByName(opts.logFormat)
+	if opts.utc {
+		r.ReportZone = time.UTC
+	}
+	return r, nil
+}
+
+// processEvents читает события построчно и передает их в гонку.
+// Ошибки разбора строк и события, которые гонка не смогла применить,
+// передаются в onError, обработка при этом продолжается.
+// Возвращает количество строк, которые не удалось разобрать; отклоненные
+// гонкой события учитываются в ее нарушениях
+func processEvents(r *race.Race, input io.Reader, onError func(lineNumber int, line string, err error)) (int, error) {
+	scanner := bufio.NewScanner(input)
+	lineNumber := 0
+	unparsed := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue // Пропускаем пустые строки
+		}
+
+		event, err := events.ParseEvent(line)
+		if err != nil {
+			unparsed++
+			onError(lineNumber, line, err)
+			continue
+		}
+		if err := r.HandleEvent(event); err != nil {
+			onError(lineNumber, line, err)
+		}
+	}
+	return unparsed, scanner.Err()
+}
+
+// writeResults выводит итоговый отчет в выбранном формате
+func writeResults(w io.Writer, r *race.Race, format string) error {
+	switch format {
+	case "json":
+		return r.WriteJSON(w)
+	case "csv":
+		return r.WriteCSV(w)
+	}
+	fmt.Fprintln(w, "\n=== РЕЗУЛЬТАТЫ ГОНКИ ===")
+	r.WriteResults(w)
+	return nil
+}
+
+// saveResults записывает итоговый отчет в файл results.<формат> в папке dir
+func saveResults(dir string, r *race.Race, format string) (string, error) {
+	path := filepath.Join(dir, "results."+format)
+	file, err := os.Create(path)
+	if err != nil {
+		return path, err
+	}
+	defer file.Close()
+
+	if err := writeResults(file, r, format); err != nil {
+		return path, err
+	}
+	return path, file.Close()
+}
+
+func runProcess(args []string) int {
+	opts, err := parseOptions("process", args)
+	if err != nil {
+		return exitCode(err)
+	}
+
+	// Создаем папку для логов, если ее нет
+	if err := os.MkdirAll(opts.outDir, 0755); err != nil {
+		log.Printf("Ошибка создания папки для логов: %v", err)
+		return 1
+	}
+
+	// Настройка логгера для событий
+	eventsLogPath := filepath.Join(opts.outDir, "events.log")
+	eventsLogFile, err := os.Create(eventsLogPath)
+	if err != nil {
+		log.Printf("Ошибка создания файла логов событий: %v", err)
+		return 1
+	}
+	defer eventsLogFile.Close()
+	eventsLogger := log.New(eventsLogFile, "", 0) // Без временных меток для чистого вывода
+
+	// Настройка логгера для ошибок
+	errorsLogPath := filepath.Join(opts.outDir, "errors.log")
+	errorLogFile, err := os.Create(errorsLogPath)
+	if err != nil {
+		log.Printf("Ошибка создания файла логов ошибок: %v", err)
+		return 1
+	}
+	defer errorLogFile.Close()
+	errorLogger := log.New(errorLogFile, "", log.LstdFlags|log.Lshortfile)
+
+	r, err := loadRace(opts)
+	if err != nil {
+		errorLogger.Print(err)
+		log.Print(err)
+		return 1
+	}
+
+	input, err := openEvents(opts.eventsPath)
+	if err != nil {
+		errorLogger.Printf("Ошибка открытия файла событий: %v", err)
+		log.Printf("Ошибка открытия файла событий: %v", err)
+		return 1
+	}
+	defer input.Close()
+
+	unparsed, err := processEvents(r, input, func(lineNumber int, line string, err error) {
+		errorLogger.Printf("Строка %d: %v (содержимое: %q)", lineNumber, err, line)
+	})
+	if err != nil {
+		errorLogger.Printf("Ошибка чтения событий: %v", err)
+		log.Printf("Ошибка чтения событий: %v", err)
+		return 1
+	}
+
+	for _, event := range r.EventLog {
+		eventsLogger.Println(r.Renderer.Render(r.ReportEvent(event)))
+	}
+
+	// Текстовый отчет печатается вслед за журналом, машинные форматы
+	// сохраняются в файл, чтобы не смешиваться с журналом в stdout
+	resultsPath := ""
+	if opts.format == "text" {
+		writeResults(os.Stdout, r, opts.format)
+	} else {
+		resultsPath, err = saveResults(opts.outDir, r, opts.format)
+		if err != nil {
+			errorLogger.Printf("Ошибка записи результатов: %v", err)
+			log.Printf("Ошибка записи результатов: %v", err)
+			return 1
+		}
+	}
+
+	if unparsed > 0 || len(r.Violations) > 0 {
+		fmt.Printf("\nСтрок с ошибками разбора: %d. %s\n", unparsed, r.ViolationSummaryText())
+	}
+	if unlisted := r.UnlistedAthletes(); len(unlisted) > 0 {
+		errorLogger.Printf("Участники не из ростера: %s", joinIDs(unlisted))
+		fmt.Printf("\nУчастники не из ростера: %s\n", joinIDs(unlisted))
+	}
+
+	// Информация о логах
+	fmt.Printf("\nЛоги сохранены в папке %s:\n", opts.outDir)
+	fmt.Printf("- События: %s\n", eventsLogPath)
+	if resultsPath != "" {
+		fmt.Printf("- Результаты: %s\n", resultsPath)
+	}
+	if stat, err := errorLogFile.Stat(); err == nil && stat.Size() > 0 {
+		fmt.Printf("- Ошибки: %s\n", errorsLogPath)
+	}
+	return 0
+}
+
+func runValidate(args []string) int {
+	opts, err := parseOptions("validate", args)
+	if err != nil {
+		return exitCode(err)
+	}
+
+	cfg, err := configs.LoadConfig(opts.configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: ошибка загрузки конфигурации: %v\n", opts.configPath, err)
+		return 1
+	}
+	roster, err := loadRoster(opts)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", opts.rosterPath, err)
+		return 1
+	}
+
+	input, err := openEvents(opts.eventsPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Ошибка открытия файла событий: %v\n", err)
+		return 1
+	}
+	defer input.Close()
+
+	diagnostics, err := validate.Events(input, cfg, roster)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Ошибка проверки событий: %v\n", err)
+		return 1
+	}
+
+	errorsCount := 0
+	for _, d := range diagnostics {
+		if d.Severity == validate.SeverityError {
+			errorsCount++
+		}
+		fmt.Printf("%s:%s\n", opts.eventsPath, d)
+	}
+	fmt.Printf("Ошибок: %d, предупреждений: %d\n", errorsCount, len(diagnostics)-errorsCount)
+
+	if validate.HasErrors(diagnostics) {
+		return 1
+	}
+	return 0
+}
+
+func runReport(args []string) int {
+	opts, err := parseOptions("report", args)
+	if err != nil {
+		return exitCode(err)
+	}
+
+	r, err := loadRace(opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	r.Output = io.Discard
+
+	input, err := openEvents(opts.eventsPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Ошибка открытия файла событий: %v\n", err)
+		return 1
+	}
+	defer input.Close()
+
+	unparsed, err := processEvents(r, input, func(lineNumber int, line string, err error) {
+		fmt.Fprintf(os.Stderr, "Строка %d: %v (содержимое: %q)\n", lineNumber, err, line)
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Ошибка чтения событий: %v\n", err)
+		return 1
+	}
+	if unparsed > 0 || len(r.Violations) > 0 {
+		fmt.Fprintf(os.Stderr, "Строк с ошибками разбора: %d. %s\n", unparsed, r.ViolationSummaryText())
+	}
+	if unlisted := r.UnlistedAthletes(); len(unlisted) > 0 {
+		fmt.Fprintf(os.Stderr, "Участники не из ростера: %s\n", joinIDs(unlisted))
+	}
+
+	if err := writeResults(os.Stdout, r, opts.format); err != nil {
+		fmt.Fprintf(os.Stderr, "Ошибка вывода результатов: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// joinIDs перечисляет ID участников через запятую
+func joinIDs(ids []int) string {
+	parts := make([]string, len(ids))
+	for i, id := range ids {
+		parts[i] = strconv.Itoa(id)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// exitCode возвращает код завершения для ошибки разбора флагов:
+// запрос справки (-h) не считается ошибкой
+func exitCode(err error) int {
+	if err == flag.ErrHelp {
+		return 0
+	}
+	return 2
+}
@@ -1,8 +1,11 @@
 package configs
 
 import (
-	"encoding/json"
+	"biathlon-prototype/utils"
+	"fmt"
 	"os"
+	"strings"
+	"time"
 )
 
 type Config struct {
@@ -12,17 +15,179 @@ type Config struct {
 	FiringLines int    `json:"firingLines"` //Количество огневых рубежей на круг
 	Start       string `json:"start"`       //Планируемое время старта первого участника
 	StartDelta  string `json:"startDelta"`  //Планируемый интервал между стартами
+	Format      string `json:"format"`      //Формат гонки: sprint (по умолчанию), individual, pursuit, mass-start, relay
+	Date        string `json:"date"`        //Дата гонки ГГГГ-ММ-ДД (необязательно), к ней привязываются времена без даты
+	TimeZone    string `json:"timeZone"`    //Часовой пояс IANA, например Europe/Oslo (необязательно, по умолчанию UTC); требует даты
+
+	LapLens     []int    `json:"lapLens"`     //Длины кругов по порядку (необязательно, вместо LapLen)
+	Stages      []string `json:"stages"`      //Положения на огневых рубежах по порядку прохождения: prone, standing (необязательно)
+	PenaltyLens []int    `json:"penaltyLens"` //Длины штрафных кругов по номерам огневых рубежей (необязательно, вместо PenaltyLen)
+
+	PenaltySpeed       float64 `json:"penaltySpeed"`       //Скорость (м/с), быстрее которой штрафной круг не пройти (необязательно, по умолчанию DefaultPenaltySpeed)
+	SkippedLoopPenalty string  `json:"skippedLoopPenalty"` //Штрафное время за каждый непройденный штрафной круг ЧЧ:ММ:СС (необязательно, по умолчанию DefaultSkippedLoopPenalty; 00:00:00 - только отмечать)
+
+	Legs int `json:"legs"` //Этапов в эстафете (необязательно, по умолчанию DefaultRelayLegs); laps и firingLines задаются на один этап
+}
+
+// DefaultPenaltySpeed - скорость на штрафном круге, которую не превышают даже
+// сильнейшие биатлонисты. Быстрейшие проходят 150 м примерно за 25 секунд
+// (6 м/с); порог взят с запасом в треть, чтобы неточные отметки времени
+// и короткие круги не давали ложных подозрений на пропуск
+const DefaultPenaltySpeed = 8.0
+
+// DefaultSkippedLoopPenalty - штрафное время за каждый непройденный штрафной
+// круг, если поле skippedLoopPenalty не задано. Как в правилах IBU - 2 минуты,
+// намного больше времени на самом круге, чтобы пропуск не давал выигрыша
+const DefaultSkippedLoopPenalty = 2 * time.Minute
+
+// DefaultRelayLegs - этапов в эстафете, если поле legs не задано
+const DefaultRelayLegs = 4
+
+// Formats - форматы гонки, которые понимает race.FormatByName. Совпадение
+// со списком форматов race проверяет тест пакета race
+var Formats = []string{"sprint", "individual", "pursuit", "mass-start", "relay"}
+
+// FieldError - проблема в одном поле конфигурации
+type FieldError struct {
+	Field   string // Имя поля в JSON
+	Message string
+}
+
+func (e FieldError) Error() string {
+	return e.Field + ": " + e.Message
 }
 
-// LoadConfig читает конфигурационный JSON-файл и возвращает структуру Config
+// ValidationError - все проблемы конфигурации, найденные за одну проверку
+type ValidationError []FieldError
+
+func (e ValidationError) Error() string {
+	problems := make([]string, len(e))
+	for i, fieldErr := range e {
+		problems[i] = fieldErr.Error()
+	}
+	return "некорректная конфигурация: " + strings.Join(problems, "; ")
+}
+
+// LoadConfig читает конфигурационный файл и возвращает структуру Config.
+// Формат определяется по расширению: .json, .yaml/.yml или .toml.
+// Конфигурация проверяется через Validate
 func LoadConfig(filename string) (Config, error) {
 	var config Config
 
+	decode, err := decoderFor(filename)
+	if err != nil {
+		return config, err
+	}
+
 	data, err := os.ReadFile(filename)
 	if err != nil {
 		return config, err
 	}
 
-	err = json.Unmarshal(data, &config)
-	return config, err
+	if err := unmarshalConfig(data, decode, &config); err != nil {
+		return config, err
+	}
+	return config, config.Validate()
+}
+
+// Validate проверяет все поля конфигурации и возвращает ValidationError
+// со всеми найденными проблемами или nil
+func (c Config) Validate() error {
+	var problems ValidationError
+	report := func(field, format string, args ...interface{}) {
+		problems = append(problems, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if c.Laps < 1 {
+		report("laps", "количество кругов должно быть не меньше 1, указано %d", c.Laps)
+	}
+	if c.LapLen <= 0 && len(c.LapLens) == 0 {
+		report("lapLen", "длина круга должна быть положительной, указано %d", c.LapLen)
+	}
+	// В индивидуальной гонке промах штрафуется временем, штрафной круг не нужен
+	penaltyRequired := c.Format != "individual"
+	if c.PenaltyLen < 0 || (c.PenaltyLen == 0 && penaltyRequired && len(c.PenaltyLens) == 0) {
+		report("penaltyLen", "длина штрафного круга должна быть положительной, указано %d", c.PenaltyLen)
+	}
+	if c.FiringLines < 1 {
+		report("firingLines", "количество огневых рубежей должно быть не меньше 1, указано %d", c.FiringLines)
+	}
+
+	if len(c.LapLens) > 0 && len(c.LapLens) != c.Laps {
+		report("lapLens", "задано длин кругов: %d, а кругов: %d", len(c.LapLens), c.Laps)
+	}
+	for i, length := range c.LapLens {
+		if length <= 0 {
+			report("lapLens", "длина круга %d должна быть положительной, указано %d", i+1, length)
+		}
+	}
+	if len(c.PenaltyLens) > 0 && len(c.PenaltyLens) != c.FiringLines {
+		report("penaltyLens", "задано длин штрафных кругов: %d, а огневых рубежей: %d", len(c.PenaltyLens), c.FiringLines)
+	}
+	for i, length := range c.PenaltyLens {
+		if length < 0 || (length == 0 && penaltyRequired) {
+			report("penaltyLens", "длина штрафного круга рубежа %d должна быть положительной, указано %d", i+1, length)
+		}
+	}
+	for i, position := range c.Stages {
+		if position != PositionProne && position != PositionStanding {
+			report("stages", "неизвестное положение %q на рубеже %d (доступны: %s, %s)", position, i+1, PositionProne, PositionStanding)
+		}
+	}
+
+	if _, err := time.Parse("15:04:05", c.Start); err != nil {
+		report("start", "некорректное время %q, ожидается ЧЧ:ММ:СС", c.Start)
+	}
+	if delta, err := utils.ParseDelta(c.StartDelta); err != nil {
+		report("startDelta", "некорректный интервал %q, ожидается ЧЧ:ММ:СС", c.StartDelta)
+	} else if delta <= 0 {
+		report("startDelta", "интервал между стартами должен быть положительным, указано %q", c.StartDelta)
+	}
+
+	if c.PenaltySpeed < 0 {
+		report("penaltySpeed", "скорость должна быть положительной, указано %g", c.PenaltySpeed)
+	}
+	if c.SkippedLoopPenalty != "" {
+		if penalty, err := utils.ParseDelta(c.SkippedLoopPenalty); err != nil || penalty < 0 {
+			report("skippedLoopPenalty", "некорректное штрафное время %q, ожидается ЧЧ:ММ:СС", c.SkippedLoopPenalty)
+		}
+	}
+
+	if c.Legs < 0 {
+		report("legs", "количество этапов должно быть положительным, указано %d", c.Legs)
+	} else if c.Legs > 0 && c.Format != "relay" {
+		report("legs", "этапы задаются только для эстафеты (format: relay)")
+	}
+
+	if c.Format != "" && !contains(Formats, c.Format) {
+		report("format", "неизвестный формат %q (доступны: %s)", c.Format, strings.Join(Formats, ", "))
+	}
+
+	if c.Date != "" {
+		if _, err := time.Parse(utils.DateLayout, c.Date); err != nil {
+			report("date", "некорректная дата %q, ожидается ГГГГ-ММ-ДД", c.Date)
+		}
+	}
+	if c.TimeZone != "" {
+		if _, err := time.LoadLocation(c.TimeZone); err != nil {
+			report("timeZone", "неизвестный часовой пояс %q", c.TimeZone)
+		}
+		if c.Date == "" {
+			report("timeZone", "часовой пояс требует даты гонки в поле date")
+		}
+	}
+
+	if len(problems) > 0 {
+		return problems
+	}
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
 }
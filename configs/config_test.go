@@ -88,7 +88,130 @@ func TestLoadConfig(t *testing.T) {
 	})
 }
 
+func validConfig() Config {
+	return Config{
+		Laps:        3,
+		LapLen:      4000,
+		PenaltyLen:  150,
+		FiringLines: 2,
+		Start:       "10:00:00",
+		StartDelta:  "00:01:00",
+	}
+}
+
 func TestConfigValidation(t *testing.T) {
-	t.Run("Config validation", func(t *testing.T) {
+	t.Run("Valid config", func(t *testing.T) {
+		cfg := validConfig()
+		if err := cfg.Validate(); err != nil {
+			t.Errorf("Validate() error = %v, want nil", err)
+		}
+
+		cfg.Format = "individual"
+		cfg.PenaltyLen = 0
+		cfg.StartDelta = "30s"
+		cfg.Date = "2026-03-01"
+		cfg.TimeZone = "Europe/Oslo"
+		if err := cfg.Validate(); err != nil {
+			t.Errorf("Validate() error = %v, want nil", err)
+		}
+
+		cfg = validConfig()
+		cfg.Format = "relay"
+		cfg.Legs = 3
+		if err := cfg.Validate(); err != nil {
+			t.Errorf("Validate() error = %v, want nil", err)
+		}
+
+		// Профиль трассы заменяет общие длины
+		cfg = validConfig()
+		cfg.LapLen = 0
+		cfg.PenaltyLen = 0
+		cfg.LapLens = []int{3000, 3500, 4000}
+		cfg.PenaltyLens = []int{150, 100}
+		if err := cfg.Validate(); err != nil {
+			t.Errorf("Validate() error = %v, want nil", err)
+		}
+	})
+
+	tests := []struct {
+		name   string
+		modify func(c *Config)
+		field  string
+	}{
+		{"Zero laps", func(c *Config) { c.Laps = 0 }, "laps"},
+		{"Negative lap length", func(c *Config) { c.LapLen = -1 }, "lapLen"},
+		{"Negative penalty length", func(c *Config) { c.PenaltyLen = -150 }, "penaltyLen"},
+		{"No penalty loop in sprint", func(c *Config) { c.PenaltyLen = 0 }, "penaltyLen"},
+		{"No firing lines", func(c *Config) { c.FiringLines = 0 }, "firingLines"},
+		{"Bad start", func(c *Config) { c.Start = "10 am" }, "start"},
+		{"Empty start", func(c *Config) { c.Start = "" }, "start"},
+		{"Bad start delta", func(c *Config) { c.StartDelta = "00:xx:30" }, "startDelta"},
+		{"Zero start delta", func(c *Config) { c.StartDelta = "00:00:00" }, "startDelta"},
+		{"Unknown format", func(c *Config) { c.Format = "team-sprint" }, "format"},
+		{"Negative relay legs", func(c *Config) { c.Format = "relay"; c.Legs = -4 }, "legs"},
+		{"Legs outside relay", func(c *Config) { c.Legs = 4 }, "legs"},
+		{"Bad date", func(c *Config) { c.Date = "01.03.2026" }, "date"},
+		{"Unknown time zone", func(c *Config) { c.Date = "2026-03-01"; c.TimeZone = "Mars/Olympus" }, "timeZone"},
+		{"Time zone without date", func(c *Config) { c.TimeZone = "Europe/Oslo" }, "timeZone"},
+		{"Lap lengths count", func(c *Config) { c.LapLens = []int{3000, 3000} }, "lapLens"},
+		{"Non-positive lap length", func(c *Config) { c.LapLens = []int{3000, 0, 3000} }, "lapLens"},
+		{"Penalty lengths count", func(c *Config) { c.PenaltyLens = []int{150} }, "penaltyLens"},
+		{"Negative penalty loop", func(c *Config) { c.PenaltyLens = []int{150, -1} }, "penaltyLens"},
+		{"Unknown position", func(c *Config) { c.Stages = []string{"prone", "kneeling"} }, "stages"},
+		{"Negative penalty speed", func(c *Config) { c.PenaltySpeed = -8 }, "penaltySpeed"},
+		{"Bad skipped loop penalty", func(c *Config) { c.SkippedLoopPenalty = "2 минуты" }, "skippedLoopPenalty"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			tt.modify(&cfg)
+
+			err := cfg.Validate()
+			problems, ok := err.(ValidationError)
+			if !ok {
+				t.Fatalf("Validate() error = %v, want ValidationError", err)
+			}
+			if len(problems) != 1 || problems[0].Field != tt.field {
+				t.Errorf("Validate() problems = %v, want one problem in %s", problems, tt.field)
+			}
+		})
+	}
+
+	t.Run("All problems at once", func(t *testing.T) {
+		cfg := Config{Laps: 0, LapLen: -1, FiringLines: 0, Start: "bad", StartDelta: "bad"}
+
+		problems, ok := cfg.Validate().(ValidationError)
+		if !ok {
+			t.Fatal("Validate() expected ValidationError")
+		}
+
+		want := []string{"laps", "lapLen", "penaltyLen", "firingLines", "start", "startDelta"}
+		if len(problems) != len(want) {
+			t.Fatalf("Validate() problems = %v, want %d problems", problems, len(want))
+		}
+		for i, field := range want {
+			if problems[i].Field != field {
+				t.Errorf("Problem %d field = %s, want %s", i, problems[i].Field, field)
+			}
+		}
+	})
+
+	t.Run("Loader validates", func(t *testing.T) {
+		tmpFile, err := os.CreateTemp("", "config_bad_*.json")
+		if err != nil {
+			t.Fatalf("Failed to create temp file: %v", err)
+		}
+		defer os.Remove(tmpFile.Name())
+
+		if _, err := tmpFile.WriteString(`{"laps": 0, "lapLen": 4000, "penaltyLen": 150, "firingLines": 1, "start": "10:00:00", "startDelta": "00:01:00"}`); err != nil {
+			t.Fatalf("Failed to write to temp file: %v", err)
+		}
+		tmpFile.Close()
+
+		_, err = LoadConfig(tmpFile.Name())
+		if _, ok := err.(ValidationError); !ok {
+			t.Errorf("LoadConfig() error = %v, want ValidationError", err)
+		}
 	})
 }
@@ -0,0 +1,56 @@
+package configs
+
+// Положения для стрельбы на огневом рубеже
+const (
+	PositionProne    = "prone"    // Лежа
+	PositionStanding = "standing" // Стоя
+)
+
+// Профиль трассы задается необязательными полями LapLens, Stages и
+// PenaltyLens. Если поле не задано, действуют общие LapLen и PenaltyLen
+
+// LapLength возвращает длину круга lap (с 1)
+func (c Config) LapLength(lap int) int {
+	if lap >= 1 && lap <= len(c.LapLens) {
+		return c.LapLens[lap-1]
+	}
+	return c.LapLen
+}
+
+// DistanceLength возвращает длину первых laps кругов дистанции
+func (c Config) DistanceLength(laps int) int {
+	total := 0
+	for lap := 1; lap <= laps; lap++ {
+		total += c.LapLength(lap)
+	}
+	return total
+}
+
+// PenaltyLength возвращает длину штрафного круга огневого рубежа firingLine (с 1).
+// Для неизвестного рубежа возвращается общий PenaltyLen
+func (c Config) PenaltyLength(firingLine int) int {
+	if firingLine >= 1 && firingLine <= len(c.PenaltyLens) {
+		return c.PenaltyLens[firingLine-1]
+	}
+	return c.PenaltyLen
+}
+
+// StagePosition возвращает положение для стрельбы на огневом рубеже stage
+// (с 1, по порядку прохождения) или пустую строку, если порядок не задан
+func (c Config) StagePosition(stage int) string {
+	if stage >= 1 && stage <= len(c.Stages) {
+		return c.Stages[stage-1]
+	}
+	return ""
+}
+
+// PositionName возвращает русское название положения для стрельбы
+func PositionName(position string) string {
+	switch position {
+	case PositionProne:
+		return "лежа"
+	case PositionStanding:
+		return "стоя"
+	}
+	return position
+}
@@ -0,0 +1,44 @@
+package configs
+
+import "testing"
+
+func TestCourseProfile(t *testing.T) {
+	cfg := validConfig()
+
+	// Без профиля действуют общие длины
+	if got := cfg.LapLength(2); got != 4000 {
+		t.Errorf("LapLength(2) = %d, want 4000", got)
+	}
+	if got := cfg.PenaltyLength(1); got != 150 {
+		t.Errorf("PenaltyLength(1) = %d, want 150", got)
+	}
+	if got := cfg.StagePosition(1); got != "" {
+		t.Errorf("StagePosition(1) = %q, want empty", got)
+	}
+
+	cfg.LapLens = []int{3000, 3500, 4000}
+	cfg.PenaltyLens = []int{150, 100}
+	cfg.Stages = []string{PositionProne, PositionStanding}
+
+	if got := cfg.LapLength(2); got != 3500 {
+		t.Errorf("LapLength(2) = %d, want 3500", got)
+	}
+	if got := cfg.DistanceLength(2); got != 6500 {
+		t.Errorf("DistanceLength(2) = %d, want 6500", got)
+	}
+	if got := cfg.PenaltyLength(2); got != 100 {
+		t.Errorf("PenaltyLength(2) = %d, want 100", got)
+	}
+	if got := cfg.PenaltyLength(0); got != 150 {
+		t.Errorf("PenaltyLength(0) = %d, want default 150", got)
+	}
+	if got := cfg.StagePosition(2); got != PositionStanding {
+		t.Errorf("StagePosition(2) = %q, want %q", got, PositionStanding)
+	}
+	if got := cfg.StagePosition(3); got != "" {
+		t.Errorf("StagePosition(3) = %q, want empty", got)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("Validate() error = %v, want nil", err)
+	}
+}
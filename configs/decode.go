@@ -0,0 +1,261 @@
+package configs
+
+import (
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Конфигурация гонки - плоский набор полей, поэтому для YAML и TOML
+// разбирается только нужное подмножество: пары ключ-значение верхнего
+// уровня, строки, числа, логические значения и массивы из них, комментарии.
+// Разобранные значения переводятся в JSON и читаются по тем же тегам Config
+
+// decodeFunc разбирает файл конфигурации в набор полей верхнего уровня
+type decodeFunc func(data []byte) (map[string]interface{}, error)
+
+// decoderFor выбирает разбор по расширению файла. Для JSON возвращает nil:
+// он читается напрямую через encoding/json
+func decoderFor(filename string) (decodeFunc, error) {
+	switch ext := strings.ToLower(filepath.Ext(filename)); ext {
+	case ".json":
+		return nil, nil
+	case ".yaml", ".yml":
+		return decodeYAML, nil
+	case ".toml":
+		return decodeTOML, nil
+	default:
+		return nil, fmt.Errorf("неизвестный формат конфигурации %q: ожидается .json, .yaml, .yml или .toml", ext)
+	}
+}
+
+// unmarshalConfig разбирает data выбранным decode и заполняет config
+func unmarshalConfig(data []byte, decode decodeFunc, config *Config) error {
+	if decode == nil {
+		return json.Unmarshal(data, config)
+	}
+	values, err := decode(data)
+	if err != nil {
+		return err
+	}
+	encoded, err := json.Marshal(values)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(encoded, config)
+}
+
+// decodeYAML разбирает YAML-документ из пар "ключ: значение". Значением
+// может быть скаляр, массив [a, b] или список из строк "- a" с отступом
+func decodeYAML(data []byte) (map[string]interface{}, error) {
+	values := make(map[string]interface{})
+	listKey := "" // Ключ, для которого собирается список "- значение"
+
+	for i, rawLine := range strings.Split(string(data), "\n") {
+		lineNumber := i + 1
+		line := strings.TrimRight(stripComment(rawLine, true), " \t\r")
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || trimmed == "---" || trimmed == "..." {
+			continue
+		}
+
+		indented := line[0] == ' ' || line[0] == '\t'
+		if indented || strings.HasPrefix(trimmed, "- ") || trimmed == "-" {
+			if listKey == "" || !strings.HasPrefix(trimmed, "-") {
+				return nil, fmt.Errorf("строка %d: вложенные значения не поддерживаются", lineNumber)
+			}
+			value, err := parseScalar(strings.TrimSpace(strings.TrimPrefix(trimmed, "-")), true)
+			if err != nil {
+				return nil, fmt.Errorf("строка %d: %v", lineNumber, err)
+			}
+			values[listKey] = append(values[listKey].([]interface{}), value)
+			continue
+		}
+		listKey = ""
+
+		key, rawValue, found := cutKey(trimmed, ":")
+		if !found {
+			return nil, fmt.Errorf("строка %d: ожидается \"ключ: значение\"", lineNumber)
+		}
+		if _, exists := values[key]; exists {
+			return nil, fmt.Errorf("строка %d: ключ %q уже задан", lineNumber, key)
+		}
+		if rawValue == "" {
+			// Значение - список на следующих строках
+			listKey = key
+			values[key] = []interface{}{}
+			continue
+		}
+		value, err := parseScalar(rawValue, true)
+		if err != nil {
+			return nil, fmt.Errorf("строка %d: %v", lineNumber, err)
+		}
+		values[key] = value
+	}
+	return values, nil
+}
+
+// decodeTOML разбирает TOML-документ из пар "ключ = значение" без таблиц.
+// Строки обязательно в кавычках, массивы записываются в одну строку
+func decodeTOML(data []byte) (map[string]interface{}, error) {
+	values := make(map[string]interface{})
+
+	for i, rawLine := range strings.Split(string(data), "\n") {
+		lineNumber := i + 1
+		line := strings.TrimSpace(stripComment(rawLine, false))
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			return nil, fmt.Errorf("строка %d: таблицы TOML не поддерживаются", lineNumber)
+		}
+
+		key, rawValue, found := cutKey(line, "=")
+		if !found || rawValue == "" {
+			return nil, fmt.Errorf("строка %d: ожидается \"ключ = значение\"", lineNumber)
+		}
+		if _, exists := values[key]; exists {
+			return nil, fmt.Errorf("строка %d: ключ %q уже задан", lineNumber, key)
+		}
+		value, err := parseScalar(rawValue, false)
+		if err != nil {
+			return nil, fmt.Errorf("строка %d: %v", lineNumber, err)
+		}
+		values[key] = value
+	}
+	return values, nil
+}
+
+// cutKey делит строку по первому разделителю на ключ и значение.
+// В YAML после двоеточия должен идти пробел или конец строки,
+// иначе "09:30:00" считалось бы ключом
+func cutKey(line, separator string) (key, value string, found bool) {
+	for offset := 0; ; {
+		i := strings.Index(line[offset:], separator)
+		if i < 0 {
+			return "", "", false
+		}
+		i += offset
+		rest := line[i+len(separator):]
+		if separator == ":" && rest != "" && rest[0] != ' ' && rest[0] != '\t' {
+			offset = i + 1
+			continue
+		}
+		key = strings.TrimSpace(line[:i])
+		if key == "" || strings.ContainsAny(key, " \t\"'") {
+			return "", "", false
+		}
+		return key, strings.TrimSpace(rest), true
+	}
+}
+
+// stripComment отбрасывает комментарий, начинающийся с # вне кавычек.
+// В YAML # начинает комментарий только в начале строки или после пробела
+func stripComment(line string, needSpace bool) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		ch := line[i]
+		switch {
+		case quote != 0:
+			if ch == '\\' && quote == '"' {
+				i++
+			} else if ch == quote {
+				quote = 0
+			}
+		case ch == '"' || ch == '\'':
+			quote = ch
+		case ch == '#' && (!needSpace || i == 0 || line[i-1] == ' ' || line[i-1] == '\t'):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// parseScalar разбирает значение: строку в кавычках, массив, число или
+// логическое значение. Строки без кавычек допустимы, если bareStrings
+func parseScalar(text string, bareStrings bool) (interface{}, error) {
+	switch {
+	case text == "":
+		return nil, fmt.Errorf("пустое значение")
+
+	case text[0] == '"':
+		value, err := strconv.Unquote(text)
+		if err != nil {
+			return nil, fmt.Errorf("некорректная строка %s", text)
+		}
+		return value, nil
+
+	case text[0] == '\'':
+		if len(text) < 2 || text[len(text)-1] != '\'' || strings.Contains(text[1:len(text)-1], "'") {
+			return nil, fmt.Errorf("некорректная строка %s", text)
+		}
+		return text[1 : len(text)-1], nil
+
+	case text[0] == '[':
+		if text[len(text)-1] != ']' {
+			return nil, fmt.Errorf("массив должен заканчиваться ] в той же строке")
+		}
+		items := []interface{}{}
+		for _, item := range splitItems(text[1 : len(text)-1]) {
+			value, err := parseScalar(item, bareStrings)
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, value)
+		}
+		return items, nil
+
+	case text == "true" || text == "false":
+		return text == "true", nil
+	}
+
+	number := text
+	if !bareStrings {
+		number = strings.ReplaceAll(number, "_", "") // В TOML 1_000 - это 1000
+	}
+	if value, err := strconv.ParseInt(number, 10, 64); err == nil {
+		return value, nil
+	}
+	if value, err := strconv.ParseFloat(number, 64); err == nil {
+		return value, nil
+	}
+
+	if !bareStrings {
+		return nil, fmt.Errorf("строковое значение %s должно быть в кавычках", text)
+	}
+	return text, nil
+}
+
+// splitItems делит содержимое массива по запятым вне кавычек и скобок
+func splitItems(text string) []string {
+	var items []string
+	var quote byte
+	depth, start := 0, 0
+	for i := 0; i < len(text); i++ {
+		ch := text[i]
+		switch {
+		case quote != 0:
+			if ch == '\\' && quote == '"' {
+				i++
+			} else if ch == quote {
+				quote = 0
+			}
+		case ch == '"' || ch == '\'':
+			quote = ch
+		case ch == '[':
+			depth++
+		case ch == ']':
+			depth--
+		case ch == ',' && depth == 0:
+			items = append(items, strings.TrimSpace(text[start:i]))
+			start = i + 1
+		}
+	}
+	// Допускается завершающая запятая: [1, 2,]
+	if last := strings.TrimSpace(text[start:]); last != "" {
+		items = append(items, last)
+	}
+	return items
+}
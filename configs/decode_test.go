@@ -0,0 +1,182 @@
+package configs
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// writeConfig записывает конфигурацию во временную папку теста
+func writeConfig(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	return path
+}
+
+func TestLoadConfig_Formats(t *testing.T) {
+	want := Config{
+		Laps:        3,
+		LapLen:      4000,
+		PenaltyLen:  150,
+		FiringLines: 2,
+		Start:       "10:00:00",
+		StartDelta:  "00:01:00",
+		Format:      "sprint",
+	}
+
+	tests := []struct {
+		name    string
+		file    string
+		content string
+	}{
+		{
+			name: "JSON",
+			file: "race.json",
+			content: `{"laps": 3, "lapLen": 4000, "penaltyLen": 150, "firingLines": 2,
+				"start": "10:00:00", "startDelta": "00:01:00", "format": "sprint"}`,
+		},
+		{
+			name: "YAML",
+			file: "race.yaml",
+			content: `# Спринт на стадионе
+---
+laps: 3           # круги
+lapLen: 4000
+penaltyLen: 150
+firingLines: 2
+start: 10:00:00   # без кавычек тоже строка
+startDelta: "00:01:00"
+format: 'sprint'
+`,
+		},
+		{
+			name: "YML extension",
+			file: "RACE.YML",
+			content: "laps: 3\nlapLen: 4000\npenaltyLen: 150\nfiringLines: 2\n" +
+				"start: \"10:00:00\"\nstartDelta: 00:01:00\nformat: sprint\n",
+		},
+		{
+			name: "TOML",
+			file: "race.toml",
+			content: `# Спринт на стадионе
+laps = 3
+lapLen = 4_000        # метры
+penaltyLen = 150
+firingLines = 2
+start = "10:00:00"
+startDelta = '00:01:00'
+format = "sprint"  # формат гонки
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := LoadConfig(writeConfig(t, tt.file, tt.content))
+			if err != nil {
+				t.Fatalf("LoadConfig() error = %v", err)
+			}
+			if !reflect.DeepEqual(cfg, want) {
+				t.Errorf("LoadConfig() = %+v, want %+v", cfg, want)
+			}
+		})
+	}
+}
+
+func TestLoadConfig_FormatErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		wantErr string
+	}{
+		{"Unknown extension", "race.ini", "laps=3", "неизвестный формат конфигурации"},
+		{"YAML nested mapping", "race.yaml", "laps: 3\ncourse:\n  lapLen: 4000\n", "строка 3: вложенные значения"},
+		{"YAML duplicate key", "race.yaml", "laps: 3\nlaps: 4\n", "строка 2: ключ \"laps\" уже задан"},
+		{"YAML no separator", "race.yaml", "laps 3\n", "строка 1: ожидается"},
+		{"YAML wrong type", "race.yaml", "laps: three\n", "laps"},
+		{"TOML bare string", "race.toml", "start = 10:00:00\n", "строка 1: строковое значение"},
+		{"TOML table", "race.toml", "laps = 3\n[course]\n", "строка 2: таблицы TOML"},
+		{"TOML unterminated array", "race.toml", "lapLens = [1000,\n", "строка 1: массив"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadConfig(writeConfig(t, tt.file, tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("LoadConfig() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseScalar(t *testing.T) {
+	tests := []struct {
+		text        string
+		bareStrings bool
+		want        interface{}
+	}{
+		{"42", false, int64(42)},
+		{"-7", true, int64(-7)},
+		{"3.5", false, 3.5},
+		{"true", false, true},
+		{`"a\"b"`, false, `a"b`},
+		{`'C:\path'`, false, `C:\path`},
+		{"[1, 2, 3]", false, []interface{}{int64(1), int64(2), int64(3)}},
+		{`["prone", 'standing',]`, false, []interface{}{"prone", "standing"}},
+		{"[prone, standing]", true, []interface{}{"prone", "standing"}},
+		{"[]", false, []interface{}{}},
+		{"sprint", true, "sprint"},
+	}
+
+	for _, tt := range tests {
+		got, err := parseScalar(tt.text, tt.bareStrings)
+		if err != nil {
+			t.Errorf("parseScalar(%q) error = %v", tt.text, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseScalar(%q) = %#v, want %#v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestStripComment(t *testing.T) {
+	tests := []struct {
+		line      string
+		needSpace bool
+		want      string
+	}{
+		{`name = "a#b" # комментарий`, false, `name = "a#b" `},
+		{`name = 'a#b'#комментарий`, false, `name = 'a#b'`},
+		{`name: a#b # комментарий`, true, `name: a#b `},
+		{`# только комментарий`, true, ``},
+		{`name: "a\"#b"`, true, `name: "a\"#b"`},
+	}
+
+	for _, tt := range tests {
+		if got := stripComment(tt.line, tt.needSpace); got != tt.want {
+			t.Errorf("stripComment(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestDecodeYAML_BlockList(t *testing.T) {
+	values, err := decodeYAML([]byte("stages:\n  - prone   # лежа\n  - standing\n- prone\nlaps: 2\n"))
+	if err != nil {
+		t.Fatalf("decodeYAML() error = %v", err)
+	}
+
+	want := map[string]interface{}{
+		"stages": []interface{}{"prone", "standing", "prone"},
+		"laps":   int64(2),
+	}
+	if !reflect.DeepEqual(values, want) {
+		t.Errorf("decodeYAML() = %#v, want %#v", values, want)
+	}
+}
@@ -0,0 +1,157 @@
+package configs
+
+import (
+	"biathlon-prototype/models"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Колонки CSV-ростера. Порядок колонок в файле задается заголовком,
+// обязательны name и хотя бы одна из id, bib
+var rosterColumns = []string{"id", "bib", "name", "nation", "team", "category", "leg"}
+
+// RosterError - все проблемы ростера, найденные за одну проверку
+type RosterError []string
+
+func (e RosterError) Error() string {
+	return "некорректный ростер: " + strings.Join(e, "; ")
+}
+
+// LoadRoster читает ростер участников из CSV (строка заголовка с именами
+// колонок) или JSON (массив объектов models.Entry). Формат определяется по
+// расширению. Ростер проверяется через ValidateRoster
+func LoadRoster(filename string) ([]models.Entry, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []models.Entry
+	switch ext := strings.ToLower(filepath.Ext(filename)); ext {
+	case ".json":
+		err = json.Unmarshal(data, &entries)
+	case ".csv":
+		entries, err = decodeRosterCSV(data)
+	default:
+		return nil, fmt.Errorf("неизвестный формат ростера %q: ожидается .csv или .json", ext)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return entries, ValidateRoster(entries)
+}
+
+// decodeRosterCSV разбирает CSV-ростер. Пустые строки пропускаются
+func decodeRosterCSV(data []byte) ([]models.Entry, error) {
+	reader := csv.NewReader(bytes.NewReader(data))
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1 // Пустые колонки в конце строки можно не писать
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("пустой ростер: ожидается строка заголовка")
+	}
+
+	columns := make(map[string]int)
+	for i, name := range records[0] {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !contains(rosterColumns, name) {
+			return nil, fmt.Errorf("строка 1: неизвестная колонка %q (доступны: %s)", name, strings.Join(rosterColumns, ", "))
+		}
+		columns[name] = i
+	}
+
+	entries := make([]models.Entry, 0, len(records)-1)
+	for i, record := range records[1:] {
+		lineNumber := i + 2
+		value := func(column string) string {
+			if index, ok := columns[column]; ok && index < len(record) {
+				return strings.TrimSpace(record[index])
+			}
+			return ""
+		}
+		number := func(column string) (int, error) {
+			text := value(column)
+			if text == "" {
+				return 0, nil
+			}
+			n, err := strconv.Atoi(text)
+			if err != nil {
+				return 0, fmt.Errorf("строка %d: %s должен быть числом: %q", lineNumber, column, text)
+			}
+			return n, nil
+		}
+
+		entry := models.Entry{
+			Name:     value("name"),
+			Nation:   value("nation"),
+			Team:     value("team"),
+			Category: value("category"),
+		}
+		if entry.ID, err = number("id"); err != nil {
+			return nil, err
+		}
+		if entry.Bib, err = number("bib"); err != nil {
+			return nil, err
+		}
+		if entry.Leg, err = number("leg"); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// ValidateRoster проверяет все заявки и возвращает RosterError со всеми
+// найденными проблемами или nil. Заявки нумеруются с 1 в порядке файла
+func ValidateRoster(entries []models.Entry) error {
+	var problems RosterError
+	report := func(index int, format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf("участник %d: ", index+1)+fmt.Sprintf(format, args...))
+	}
+
+	ids := make(map[int]int)
+	bibs := make(map[int]int)
+	for i, entry := range entries {
+		if entry.Name == "" {
+			report(i, "не указано имя")
+		}
+		if entry.ID < 0 || entry.Bib < 0 {
+			report(i, "id и bib не могут быть отрицательными")
+			continue
+		}
+		if entry.Leg < 0 {
+			report(i, "этап не может быть отрицательным, указано %d", entry.Leg)
+		}
+		if entry.AthleteID() == 0 {
+			report(i, "нужен id или bib")
+			continue
+		}
+		if first, exists := ids[entry.AthleteID()]; exists {
+			report(i, "id %d уже занят участником %d", entry.AthleteID(), first+1)
+		} else {
+			ids[entry.AthleteID()] = i
+		}
+		if entry.Bib == 0 {
+			continue
+		}
+		if first, exists := bibs[entry.Bib]; exists {
+			report(i, "нагрудный номер %d уже выдан участнику %d", entry.Bib, first+1)
+		} else {
+			bibs[entry.Bib] = i
+		}
+	}
+
+	if len(problems) > 0 {
+		return problems
+	}
+	return nil
+}
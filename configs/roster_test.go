@@ -0,0 +1,80 @@
+package configs
+
+import (
+	"biathlon-prototype/models"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadRoster_Formats(t *testing.T) {
+	want := []models.Entry{
+		{ID: 1, Bib: 12, Name: "Johannes Boe", Nation: "NOR", Team: "Norway", Category: "M"},
+		{Bib: 7, Name: "Quentin Fillon Maillet", Nation: "FRA"},
+	}
+
+	tests := []struct {
+		name    string
+		file    string
+		content string
+	}{
+		{
+			name: "CSV",
+			file: "roster.csv",
+			content: "id,bib,name,nation,team,category\n" +
+				"1,12,Johannes Boe,NOR,Norway,M\n" +
+				",7,Quentin Fillon Maillet,FRA,,\n",
+		},
+		{
+			name:    "CSV with columns in any order",
+			file:    "roster.CSV",
+			content: "Name, Bib, Nation, ID, Team, Category\nJohannes Boe, 12, NOR, 1, Norway, M\nQuentin Fillon Maillet, 7, FRA\n",
+		},
+		{
+			name: "JSON",
+			file: "roster.json",
+			content: `[{"id": 1, "bib": 12, "name": "Johannes Boe", "nation": "NOR", "team": "Norway", "category": "M"},
+			{"bib": 7, "name": "Quentin Fillon Maillet", "nation": "FRA"}]`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LoadRoster(writeConfig(t, tt.file, tt.content))
+			if err != nil {
+				t.Fatalf("LoadRoster() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("LoadRoster() = %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestLoadRoster_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		wantErr string
+	}{
+		{name: "Unknown extension", file: "roster.txt", content: "", wantErr: "формат ростера"},
+		{name: "Unknown column", file: "roster.csv", content: "bib,name,club\n1,A,B\n", wantErr: "колонка \"club\""},
+		{name: "Bad bib", file: "roster.csv", content: "bib,name\nfirst,A\n", wantErr: "строка 2: bib"},
+		{name: "Missing name", file: "roster.csv", content: "bib,name\n1,\n", wantErr: "участник 1: не указано имя"},
+		{name: "No ID or bib", file: "roster.json", content: `[{"name": "A"}]`, wantErr: "нужен id или bib"},
+		{name: "Duplicate ID", file: "roster.json", content: `[{"id": 3, "name": "A"}, {"bib": 3, "name": "B"}]`, wantErr: "id 3 уже занят участником 1"},
+		{name: "Duplicate bib", file: "roster.csv", content: "id,bib,name\n1,5,A\n2,5,B\n", wantErr: "нагрудный номер 5"},
+		{name: "Bad leg", file: "roster.csv", content: "id,name,team,leg\n1,A,NOR,first\n", wantErr: "строка 2: leg"},
+		{name: "Negative leg", file: "roster.json", content: `[{"id": 1, "name": "A", "leg": -1}]`, wantErr: "этап не может быть отрицательным"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadRoster(writeConfig(t, tt.file, tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("LoadRoster() error = %v, want containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
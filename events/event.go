@@ -21,6 +21,8 @@ const (
 	EventLeavePenalty     = 9
 	EventLapFinish        = 10
 	EventCantContinue     = 11
+	EventExchange         = 12 // Передача эстафеты (параметр: ID принимающего участника)
+	EventSpareRound       = 13 // Дозаряжен запасной патрон (эстафета)
 
 	EventDisqualified = 32
 	EventFinished     = 33
@@ -45,8 +47,14 @@ func ParseEvent(line string) (Event, error) {
 		return event, fmt.Errorf("пустая строка")
 	}
 
-	// Разбиваем строку на части
+	// Разбиваем строку на части, комментарий после # отбрасываем
 	parts := strings.Fields(line)
+	for i, part := range parts {
+		if strings.HasPrefix(part, "#") {
+			parts = parts[:i]
+			break
+		}
+	}
 	if len(parts) < 3 {
 		return event, fmt.Errorf("Некорректная строка события: %s", line)
 	}
@@ -79,3 +87,10 @@ func ParseEvent(line string) (Event, error) {
 
 	return event, nil
 }
+
+// String записывает событие строкой входного файла:
+// [время] ID-события ID-участника [параметры]
+func (e Event) String() string {
+	parts := []string{"[" + utils.FormatTime(e.Time) + "]", strconv.Itoa(e.EventID), strconv.Itoa(e.AthleteID)}
+	return strings.Join(append(parts, e.Params...), " ")
+}
@@ -80,6 +80,30 @@ func TestParseEvent(t *testing.T) {
 				Raw:       "[09:45:06.000] 61 1 2",
 			},
 		},
+		{
+			name:    "Time with date",
+			input:   "[2026-01-01T00:10:00.000] 10 1",
+			wantErr: false,
+			wantEvent: Event{
+				Time:      time.Date(2026, 1, 1, 0, 10, 0, 0, time.UTC),
+				EventID:   EventLapFinish,
+				AthleteID: 1,
+				Params:    []string{},
+				Raw:       "[2026-01-01T00:10:00.000] 10 1",
+			},
+		},
+		{
+			name:    "Trailing comment",
+			input:   "[09:45:05.000] 6 1 1   # Попадание в мишень 1",
+			wantErr: false,
+			wantEvent: Event{
+				Time:      time.Date(0, 1, 1, 9, 45, 5, 0, time.UTC),
+				EventID:   EventHitSuccessful,
+				AthleteID: 1,
+				Params:    []string{"1"},
+				Raw:       "[09:45:05.000] 6 1 1   # Попадание в мишень 1",
+			},
+		},
 		{
 			name:    "Invalid event format",
 			input:   "[09:45:06.000] invalid",
@@ -146,3 +170,19 @@ func equalStringSlices(a, b []string) bool {
 	}
 	return true
 }
+
+func TestEvent_StringRoundTrip(t *testing.T) {
+	for _, line := range []string{
+		"[09:05:00.000] 2 1 09:30:00.000",
+		"[2026-03-01T09:05:00.000] 5 12 1 4",
+		"[10:25:01.000] 33 1",
+	} {
+		event, err := ParseEvent(line)
+		if err != nil {
+			t.Fatalf("ParseEvent(%q) error = %v", line, err)
+		}
+		if got := event.String(); got != line {
+			t.Errorf("String() = %q, want %q", got, line)
+		}
+	}
+}
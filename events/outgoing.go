@@ -0,0 +1,177 @@
+package events
+
+import (
+	"biathlon-prototype/utils"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Kind - тип исходящего события. Для событий, подтверждающих входящие,
+// значение совпадает с ID входящего события, собственные события системы
+// используют идентификаторы из спецификации (32, 33) и следующие за ними (34, 35)
+type Kind int
+
+// виды исходящих событий, в скобках - тип нагрузки Payload
+const (
+	KindRegistered     Kind = EventRegister         // Участник зарегистрирован
+	KindStartScheduled Kind = EventStartTimeLottery // Время старта назначено (StartScheduled)
+	KindAtStartLine    Kind = EventAtStartLine      // Участник на стартовой линии
+	KindStarted        Kind = EventStart            // Участник стартовал
+	KindAtFiringLine   Kind = EventAtFiringLine     // На огневом рубеже (AtFiringLine)
+	KindHit            Kind = EventHitSuccessful    // Попадание (Shot)
+	KindLeftFiringLine Kind = EventLeaveFiringLine  // Покинул рубеж (LeftFiringLine)
+	KindEnteredPenalty Kind = EventEnterPenalty     // Вошел на штрафные круги
+	KindLeftPenalty    Kind = EventLeavePenalty     // Покинул штрафные круги (LeftPenalty)
+	KindLapFinished    Kind = EventLapFinish        // Круг завершен (LapFinished)
+	KindCantContinue   Kind = EventCantContinue     // Не может продолжить (Reason)
+	KindExchange       Kind = EventExchange         // Передал эстафету (Exchange)
+	KindSpareLoaded    Kind = EventSpareRound       // Дозарядил запасной патрон (SpareLoaded)
+	KindDisqualified   Kind = EventDisqualified     // Дисквалифицирован (Reason, если причина есть)
+	KindFinished       Kind = EventFinished         // Финишировал
+	KindPenaltySkipped Kind = 34                    // Пройдено меньше штрафных кругов, чем положено (PenaltySkipped)
+	KindPenaltySuspect Kind = 35                    // Возможно пропущен штрафной круг: круги пройдены слишком быстро (PenaltySuspect)
+	KindNotInRoster    Kind = 36                    // Участника нет в ростере (события по нему все равно применяются)
+	KindMiss           Kind = EventHitMissed        // Промах (Shot)
+)
+
+// ReasonNotStarted - причина дисквалификации участника, не стартовавшего вовремя
+const ReasonNotStarted = "NotStarted"
+
+// Outgoing - событие, сформированное системой при обработке входящих событий
+type Outgoing struct {
+	Time      time.Time
+	Kind      Kind
+	AthleteID int
+	Payload   Payload // Полезная нагрузка, тип зависит от Kind; nil - без нагрузки
+}
+
+// Params возвращает параметры события строками в порядке формата входного файла
+func (e Outgoing) Params() []string {
+	if e.Payload == nil {
+		return nil
+	}
+	return e.Payload.Params()
+}
+
+// Renderer преобразует исходящее событие в строку для журнала
+type Renderer interface {
+	Render(e Outgoing) string
+}
+
+// RendererByName возвращает способ вывода журнала по названию: text, raw или json
+func RendererByName(name string) (Renderer, error) {
+	switch name {
+	case "", "text":
+		return TextRenderer{}, nil
+	case "raw":
+		return RawRenderer{}, nil
+	case "json":
+		return JSONRenderer{}, nil
+	}
+	return nil, fmt.Errorf("неизвестный формат журнала событий: %q", name)
+}
+
+// TextRenderer выводит событие человекочитаемой строкой на русском
+type TextRenderer struct{}
+
+func (TextRenderer) Render(e Outgoing) string {
+	prefix := fmt.Sprintf("[%s] ", utils.FormatTime(e.Time))
+
+	switch p := e.Payload.(type) {
+	case StartScheduled:
+		return prefix + fmt.Sprintf("Время старта для участника(%d) установлено жеребьевкой на %s", e.AthleteID, utils.FormatTime(p.Start))
+	case AtFiringLine:
+		if p.Lane > 0 {
+			return prefix + fmt.Sprintf("Участник(%d) на огневом рубеже(%d), позиция %d", e.AthleteID, p.Line, p.Lane)
+		}
+		return prefix + fmt.Sprintf("Участник(%d) на огневом рубеже(%d)", e.AthleteID, p.Line)
+	case Shot:
+		if e.Kind == KindMiss {
+			return prefix + fmt.Sprintf("Участник(%d) промахнулся по мишени %d", e.AthleteID, p.Target)
+		}
+		return prefix + fmt.Sprintf("Участник(%d) попал в мишень %d", e.AthleteID, p.Target)
+	case LeftFiringLine:
+		return prefix + fmt.Sprintf("Участник(%d) покинул огневой рубеж(%d) (время: %s)", e.AthleteID, p.Line, utils.FormatDuration(p.RangeTime))
+	case LeftPenalty:
+		return prefix + fmt.Sprintf("Участник(%d) покинул штрафные круги (время: %s, общий штраф: %d сек)", e.AthleteID, utils.FormatDuration(p.LoopTime), p.TotalSeconds)
+	case LapFinished:
+		return prefix + fmt.Sprintf("Участник(%d) завершил круг %d (время круга: %s, общее время: %s)", e.AthleteID, p.Lap,
+			utils.FormatDuration(p.LapTime), utils.FormatDuration(p.Total))
+	case Reason:
+		if e.Kind == KindCantContinue {
+			return prefix + fmt.Sprintf("Участник(%d) не может продолжить: %s", e.AthleteID, p.Text)
+		}
+		if p.Text == ReasonNotStarted {
+			return prefix + fmt.Sprintf("Участник(%d) дисквалифицирован (не стартовал вовремя)", e.AthleteID)
+		}
+	case Exchange:
+		return prefix + fmt.Sprintf("Участник(%d) передал эстафету участнику(%d)", e.AthleteID, p.Next)
+	case SpareLoaded:
+		return prefix + fmt.Sprintf("Участник(%d) дозарядил запасной патрон (стрельба %d, запасных: %d)", e.AthleteID, p.Stage, p.Spares)
+	case PenaltySkipped:
+		return prefix + fmt.Sprintf("Участник(%d) пропустил штрафные круги после стрельбы %d (положено: %d, пройдено: %d)",
+			e.AthleteID, p.Stage, p.Owed, p.Served)
+	case PenaltySuspect:
+		return prefix + fmt.Sprintf("Участник(%d) возможно пропустил штрафной круг после стрельбы %d (положено: %d, время на кругах: %s, минимум: %s)",
+			e.AthleteID, p.Stage, p.Owed, utils.FormatDuration(p.Served), utils.FormatDuration(p.Minimum))
+	}
+
+	switch e.Kind {
+	case KindRegistered:
+		return prefix + fmt.Sprintf("Участник(%d) зарегистрирован", e.AthleteID)
+	case KindAtStartLine:
+		return prefix + fmt.Sprintf("Участник(%d) на стартовой линии", e.AthleteID)
+	case KindStarted:
+		return prefix + fmt.Sprintf("Участник(%d) начал гонку", e.AthleteID)
+	case KindEnteredPenalty:
+		return prefix + fmt.Sprintf("Участник(%d) вошел на штрафные круги", e.AthleteID)
+	case KindDisqualified:
+		return prefix + fmt.Sprintf("Участник(%d) дисквалифицирован", e.AthleteID)
+	case KindFinished:
+		return prefix + fmt.Sprintf("Участник(%d) финишировал", e.AthleteID)
+	case KindNotInRoster:
+		return prefix + fmt.Sprintf("Участник(%d) отсутствует в ростере", e.AthleteID)
+	}
+	return prefix + fmt.Sprintf("Участник(%d): событие %d %s", e.AthleteID, e.Kind, strings.Join(e.Params(), " "))
+}
+
+// RawRenderer выводит событие в формате входного файла:
+// [время] ID-события ID-участника [параметры]
+type RawRenderer struct{}
+
+func (RawRenderer) Render(e Outgoing) string {
+	parts := []string{
+		"[" + utils.FormatTime(e.Time) + "]",
+		fmt.Sprint(int(e.Kind)),
+		fmt.Sprint(e.AthleteID),
+	}
+	parts = append(parts, e.Params()...)
+	return strings.Join(parts, " ")
+}
+
+// JSONRenderer выводит событие JSON-объектом в одну строку
+type JSONRenderer struct{}
+
+// outgoingJSON - представление исходящего события в JSON
+type outgoingJSON struct {
+	Time      string   `json:"time"`
+	Kind      int      `json:"kind"`
+	AthleteID int      `json:"athleteId"`
+	Params    []string `json:"params,omitempty"`
+}
+
+func (JSONRenderer) Render(e Outgoing) string {
+	data, err := json.Marshal(outgoingJSON{
+		Time:      utils.FormatTime(e.Time),
+		Kind:      int(e.Kind),
+		AthleteID: e.AthleteID,
+		Params:    e.Params(),
+	})
+	if err != nil {
+		// Структура состоит только из строк и чисел, ошибки здесь не ожидаются
+		return fmt.Sprintf(`{"error":%q}`, err.Error())
+	}
+	return string(data)
+}
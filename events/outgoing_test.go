@@ -0,0 +1,118 @@
+package events
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestRenderers(t *testing.T) {
+	lap := Outgoing{
+		Time:      time.Date(0, 1, 1, 10, 0, 0, 0, time.UTC),
+		Kind:      KindLapFinished,
+		AthleteID: 1,
+		Payload:   LapFinished{Lap: 1, LapTime: 30 * time.Minute, Total: 30 * time.Minute},
+	}
+	notStarted := Outgoing{
+		Time:      time.Date(0, 1, 1, 9, 31, 0, 0, time.UTC),
+		Kind:      KindDisqualified,
+		AthleteID: 2,
+		Payload:   Reason{Text: ReasonNotStarted},
+	}
+
+	testCases := []struct {
+		name     string
+		renderer Renderer
+		event    Outgoing
+		want     string
+	}{
+		{
+			name:     "Text lap finished",
+			renderer: TextRenderer{},
+			event:    lap,
+			want:     "[10:00:00.000] Участник(1) завершил круг 1 (время круга: 00:30:00.000, общее время: 00:30:00.000)",
+		},
+		{
+			name:     "Text not started in time",
+			renderer: TextRenderer{},
+			event:    notStarted,
+			want:     "[09:31:00.000] Участник(2) дисквалифицирован (не стартовал вовремя)",
+		},
+		{
+			name:     "Text finished",
+			renderer: TextRenderer{},
+			event:    Outgoing{Time: lap.Time, Kind: KindFinished, AthleteID: 3},
+			want:     "[10:00:00.000] Участник(3) финишировал",
+		},
+		{
+			name:     "Text at firing line with lane",
+			renderer: TextRenderer{},
+			event:    Outgoing{Time: lap.Time, Kind: KindAtFiringLine, AthleteID: 1, Payload: AtFiringLine{Line: 2, Lane: 12}},
+			want:     "[10:00:00.000] Участник(1) на огневом рубеже(2), позиция 12",
+		},
+		{
+			name:     "Text relay exchange",
+			renderer: TextRenderer{},
+			event:    Outgoing{Time: lap.Time, Kind: KindExchange, AthleteID: 11, Payload: Exchange{Next: 12}},
+			want:     "[10:00:00.000] Участник(11) передал эстафету участнику(12)",
+		},
+		{
+			name:     "Raw lap finished",
+			renderer: RawRenderer{},
+			event:    lap,
+			want:     "[10:00:00.000] 10 1 1 00:30:00.000 00:30:00.000",
+		},
+		{
+			name:     "Raw finished",
+			renderer: RawRenderer{},
+			event:    Outgoing{Time: lap.Time, Kind: KindFinished, AthleteID: 3},
+			want:     "[10:00:00.000] 33 3",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := tc.renderer.Render(tc.event); got != tc.want {
+				t.Errorf("Render() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestJSONRenderer(t *testing.T) {
+	event := Outgoing{
+		Time:      time.Date(0, 1, 1, 9, 31, 0, 0, time.UTC),
+		Kind:      KindDisqualified,
+		AthleteID: 2,
+		Payload:   Reason{Text: ReasonNotStarted},
+	}
+
+	var got struct {
+		Time      string   `json:"time"`
+		Kind      int      `json:"kind"`
+		AthleteID int      `json:"athleteId"`
+		Params    []string `json:"params"`
+	}
+	if err := json.Unmarshal([]byte(JSONRenderer{}.Render(event)), &got); err != nil {
+		t.Fatalf("Render() produced invalid JSON: %v", err)
+	}
+
+	if got.Time != "09:31:00.000" || got.Kind != 32 || got.AthleteID != 2 {
+		t.Errorf("Render() = %+v, want time 09:31:00.000, kind 32, athlete 2", got)
+	}
+	if !equalStringSlices(got.Params, event.Params()) {
+		t.Errorf("Render() params = %v, want %v", got.Params, event.Params())
+	}
+}
+
+func TestRendererByName(t *testing.T) {
+	for _, name := range []string{"", "text", "raw", "json"} {
+		if _, err := RendererByName(name); err != nil {
+			t.Errorf("RendererByName(%q) unexpected error = %v", name, err)
+		}
+	}
+
+	if _, err := RendererByName("xml"); err == nil {
+		t.Error("Expected error for unknown renderer, got nil")
+	}
+}
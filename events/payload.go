@@ -0,0 +1,133 @@
+package events
+
+import (
+	"biathlon-prototype/utils"
+	"strconv"
+	"time"
+)
+
+// Payload - полезная нагрузка исходящего события. Конкретный тип зависит
+// от Kind (см. комментарии к видам событий); события без нагрузки имеют nil.
+// Значения хранятся типизированными и форматируются только при выводе
+type Payload interface {
+	// Params возвращает параметры строками в порядке формата входного файла
+	Params() []string
+}
+
+// StartScheduled - нагрузка KindStartScheduled
+type StartScheduled struct {
+	Start time.Time // Назначенное время старта
+}
+
+func (p StartScheduled) Params() []string {
+	return []string{utils.FormatTime(p.Start)}
+}
+
+// AtFiringLine - нагрузка KindAtFiringLine
+type AtFiringLine struct {
+	Line int // Номер огневого рубежа
+	Lane int // Номер стрелковой позиции, 0 - не указан и не назначен
+}
+
+func (p AtFiringLine) Params() []string {
+	params := []string{strconv.Itoa(p.Line)}
+	if p.Lane > 0 {
+		params = append(params, strconv.Itoa(p.Lane))
+	}
+	return params
+}
+
+// Shot - нагрузка KindHit и KindMiss
+type Shot struct {
+	Target int // Номер мишени
+}
+
+func (p Shot) Params() []string {
+	return []string{strconv.Itoa(p.Target)}
+}
+
+// SpareLoaded - нагрузка KindSpareLoaded
+type SpareLoaded struct {
+	Stage  int // Номер стрельбы участника
+	Spares int // Запасных патронов дозаряжено на ней, включая этот
+}
+
+func (p SpareLoaded) Params() []string {
+	return []string{strconv.Itoa(p.Stage), strconv.Itoa(p.Spares)}
+}
+
+// LeftFiringLine - нагрузка KindLeftFiringLine
+type LeftFiringLine struct {
+	Line      int           // Номер огневого рубежа
+	RangeTime time.Duration // Время на рубеже
+}
+
+func (p LeftFiringLine) Params() []string {
+	return []string{strconv.Itoa(p.Line), utils.FormatDuration(p.RangeTime)}
+}
+
+// LeftPenalty - нагрузка KindLeftPenalty
+type LeftPenalty struct {
+	LoopTime     time.Duration // Время пройденного штрафного круга
+	TotalSeconds int           // Общее время на штрафных кругах, секунды
+}
+
+func (p LeftPenalty) Params() []string {
+	return []string{utils.FormatDuration(p.LoopTime), strconv.Itoa(p.TotalSeconds)}
+}
+
+// LapFinished - нагрузка KindLapFinished
+type LapFinished struct {
+	Lap     int           // Номер круга, с 1
+	LapTime time.Duration // Время круга
+	Total   time.Duration // Время от старта
+}
+
+func (p LapFinished) Params() []string {
+	return []string{strconv.Itoa(p.Lap), utils.FormatDuration(p.LapTime), utils.FormatDuration(p.Total)}
+}
+
+// Reason - нагрузка KindCantContinue и KindDisqualified
+type Reason struct {
+	Text string // Причина; для дисквалификации пусто или ReasonNotStarted
+}
+
+func (p Reason) Params() []string {
+	if p.Text == "" {
+		return nil
+	}
+	return []string{p.Text}
+}
+
+// Exchange - нагрузка KindExchange
+type Exchange struct {
+	Next int // ID участника, принявшего эстафету
+}
+
+func (p Exchange) Params() []string {
+	return []string{strconv.Itoa(p.Next)}
+}
+
+// PenaltySkipped - нагрузка KindPenaltySkipped
+type PenaltySkipped struct {
+	Stage  int // Номер стрельбы участника
+	Owed   int // Штрафных кругов положено
+	Served int // Штрафных кругов пройдено
+}
+
+func (p PenaltySkipped) Params() []string {
+	return []string{strconv.Itoa(p.Stage), strconv.Itoa(p.Owed), strconv.Itoa(p.Served)}
+}
+
+// PenaltySuspect - нагрузка KindPenaltySuspect
+type PenaltySuspect struct {
+	Stage   int           // Номер стрельбы участника
+	Owed    int           // Штрафных кругов положено
+	Served  time.Duration // Время на штрафных кругах после стрельбы
+	Minimum time.Duration // Наименьшее возможное время на положенных кругах
+}
+
+func (p PenaltySuspect) Params() []string {
+	return []string{strconv.Itoa(p.Stage), strconv.Itoa(p.Owed),
+		utils.FormatDuration(p.Served), utils.FormatDuration(p.Minimum)}
+}
@@ -12,20 +12,43 @@ const (
 	StatusDisqualified Status = "Disqualified"
 )
 
+// State - этап, на котором находится участник. По нему проверяется,
+// допустимо ли очередное событие
+type State string
+
+const (
+	StateNone         State = ""
+	StateRegistered   State = "Registered"
+	StateScheduled    State = "Scheduled"
+	StateAtStartLine  State = "AtStartLine"
+	StateRacing       State = "Racing"
+	StateAtFiringLine State = "AtFiringLine"
+	StatePenalty      State = "Penalty"
+	StateFinished     State = "Finished"
+	StateOut          State = "Out" // Сошел или дисквалифицирован
+)
+
 type Athlete struct {
 	ID               int
+	Entry            *Entry // Заявка из ростера, nil если ростер не загружен или участника в нем нет
 	RegisteredAt     time.Time
 	StartTimePlanned time.Time
 	StartTimeActual  *time.Time
 	FinishTime       *time.Time
+	PhotoFinish      int // Место по фотофинишу среди финишировавших одновременно (параметр события 33), 0 - нет
 	Status           Status
+	State            State
 	LapTimes         []time.Duration
-	PenaltyTimes     []time.Duration
+	PenaltyTimes     []time.Duration // Время каждого пройденного штрафного круга, по порядку PenaltyLoops
+	PenaltyLoops     []PenaltyLoop   // Штрафные круги по порядку прохождения
 	CurrentLap       int
 	TotalPenalty     int
+	TimePenalty      time.Duration // Штрафное время за промахи (индивидуальная гонка)
+	LoopPenalty      time.Duration // Штрафное время за непройденные штрафные круги
 	Shots            int
 	Hits             int
 	FiringLineTimes  map[int]time.Time // Время на каждом огневом рубеже
+	Stages           []ShootingStage   // Стрельбы по порядку прохождения рубежей
 	LastLapTime      time.Time         //время завершения последнего круга
 	TotalDistance    int               // Общая статистика
 	AvgSpeed         float64           // Средняя скорость
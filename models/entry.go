@@ -0,0 +1,45 @@
+package models
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Entry - заявка участника из стартового протокола (ростера)
+type Entry struct {
+	ID       int    `json:"id"`            // ID участника в событиях; 0 - совпадает с нагрудным номером
+	Bib      int    `json:"bib"`           // Нагрудный номер
+	Name     string `json:"name"`          // Имя и фамилия
+	Nation   string `json:"nation"`        // Код страны, например NOR
+	Team     string `json:"team"`          // Команда или клуб
+	Category string `json:"category"`      // Возрастная или квалификационная группа
+	Leg      int    `json:"leg,omitempty"` // Этап эстафеты, с 1; 0 - не эстафета
+}
+
+// AthleteID возвращает ID, по которому заявка связывается с событиями:
+// явный ID или, если он не указан, нагрудный номер
+func (e Entry) AthleteID() int {
+	if e.ID != 0 {
+		return e.ID
+	}
+	return e.Bib
+}
+
+// String описывает участника для отчетов: "№12 Иван Петров, RUS, Динамо, M",
+// в эстафете с этапом: "..., этап 2". Пустые поля пропускаются
+func (e Entry) String() string {
+	parts := make([]string, 0, 5)
+	name := e.Name
+	if e.Bib > 0 {
+		name = strings.TrimSpace("№" + strconv.Itoa(e.Bib) + " " + e.Name)
+	}
+	for _, part := range []string{name, e.Nation, e.Team, e.Category} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	if e.Leg > 0 {
+		parts = append(parts, "этап "+strconv.Itoa(e.Leg))
+	}
+	return strings.Join(parts, ", ")
+}
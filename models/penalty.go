@@ -0,0 +1,63 @@
+package models
+
+import "time"
+
+// PenaltyLoop - один штрафной круг: от входа (событие 8) до выхода (событие 9)
+type PenaltyLoop struct {
+	Stage   int       // Стрельба, за промахи на которой пройден круг, 0 если стрельбы не было
+	Line    int       // Огневой рубеж этой стрельбы
+	Entered time.Time // Вход на штрафной круг
+	Left    time.Time // Выход, нулевое время пока участник на круге
+}
+
+// Duration возвращает время на штрафном круге или 0, если участник еще на нем
+func (p PenaltyLoop) Duration() time.Duration {
+	if p.Left.IsZero() {
+		return 0
+	}
+	return p.Left.Sub(p.Entered)
+}
+
+// LoopsOwed возвращает, сколько штрафных кругов участник должен пройти за промахи
+func (a *Athlete) LoopsOwed() int {
+	owed := 0
+	for _, stage := range a.Stages {
+		owed += stage.LoopsOwed
+	}
+	return owed
+}
+
+// LoopsServed возвращает, сколько штрафных кругов участник прошел полностью
+func (a *Athlete) LoopsServed() int {
+	served := 0
+	for _, loop := range a.PenaltyLoops {
+		if !loop.Left.IsZero() {
+			served++
+		}
+	}
+	return served
+}
+
+// SuspectedLoops возвращает, сколько штрафных кругов участник, судя по
+// времени на них, мог не пройти (сверх явно пропущенных)
+func (a *Athlete) SuspectedLoops() int {
+	suspected := 0
+	for _, stage := range a.Stages {
+		suspected += stage.LoopsSuspected
+	}
+	return suspected
+}
+
+// SkippedLoops возвращает, сколько положенных штрафных кругов участник не прошел.
+// Учитываются только сверенные стрельбы (PenaltyChecked): пока участник на
+// рубеже или на штрафных кругах, недостача еще не известна. Лишние круги
+// после одной стрельбы не покрывают недостачу после другой
+func (a *Athlete) SkippedLoops() int {
+	skipped := 0
+	for _, stage := range a.Stages {
+		if stage.PenaltyChecked && stage.LoopsServed < stage.LoopsOwed {
+			skipped += stage.LoopsOwed - stage.LoopsServed
+		}
+	}
+	return skipped
+}
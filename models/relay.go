@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// Team - команда эстафеты, составленная по ростеру
+type Team struct {
+	ID     int    // Номер команды по порядку первой заявки в ростере, с 1
+	Name   string // Название команды (поле team ростера)
+	Nation string // Код страны первого участника команды
+	Legs   []int  // ID участников по этапам: Legs[0] бежит первый этап
+}
+
+// LegAthlete возвращает ID участника этапа leg (с 1) или 0, если этап не заполнен
+func (t *Team) LegAthlete(leg int) int {
+	if leg < 1 || leg > len(t.Legs) {
+		return 0
+	}
+	return t.Legs[leg-1]
+}
+
+// Exchange - передача эстафеты в зоне передачи
+type Exchange struct {
+	Time time.Time
+	Team string // Название команды
+	Leg  int    // Этап, который закончился передачей
+	From int    // ID передающего участника
+	To   int    // ID принимающего участника
+}
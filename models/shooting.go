@@ -0,0 +1,114 @@
+package models
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TargetsPerStage - количество мишеней на огневом рубеже, номера с 1
+const TargetsPerStage = 5
+
+// SpareRoundsPerStage - сколько запасных патронов можно дозарядить
+// на одной стрельбе в эстафете
+const SpareRoundsPerStage = 3
+
+// ShootingStage - одна стрельба участника: от прихода на огневой рубеж до ухода с него
+type ShootingStage struct {
+	Stage    int          // Порядковый номер стрельбы участника, с 1
+	Line     int          // Номер огневого рубежа (стрельбища)
+	Lane     int          // Номер стрелковой позиции, 0 если не указан
+	Position string       // Положение для стрельбы из профиля трассы: prone, standing или пусто
+	Arrived  time.Time    // Приход на рубеж
+	Left     time.Time    // Уход с рубежа, нулевое время пока участник стреляет
+	Shots    int          // Выстрелов на рубеже
+	Hits     int          // Попаданий на рубеже
+	Targets  map[int]bool // Результат по номеру мишени: true - закрыта, false - стреляли мимо
+	Spares   int          // Запасных патронов дозаряжено (эстафета)
+
+	LoopsOwed      int  // Штрафных кругов положено за промахи (в форматах со штрафными кругами)
+	LoopsServed    int  // Штрафных кругов пройдено после этой стрельбы
+	LoopsSuspected int  // Сколько кругов, судя по времени, могло быть не пройдено
+	PenaltyChecked bool // Пройденные круги уже сверены с положенными
+}
+
+// Misses возвращает количество промахов на рубеже
+func (s ShootingStage) Misses() int {
+	return s.Shots - s.Hits
+}
+
+// RangeTime возвращает время на рубеже или 0, если участник еще не ушел с него
+func (s ShootingStage) RangeTime() time.Duration {
+	if s.Left.IsZero() {
+		return 0
+	}
+	return s.Left.Sub(s.Arrived)
+}
+
+// TargetClosed сообщает, что мишень target уже закрыта на этом рубеже
+func (s ShootingStage) TargetClosed(target int) bool {
+	return s.Targets[target]
+}
+
+// TargetCard рисует карточку стрельбы: по символу на каждую мишень по порядку,
+// "●" - закрыта, "○" - стреляли мимо, "-" - не стреляли
+func (s ShootingStage) TargetCard() string {
+	var card strings.Builder
+	for target := 1; target <= TargetsPerStage; target++ {
+		hit, shot := s.Targets[target]
+		switch {
+		case hit:
+			card.WriteString("●")
+		case shot:
+			card.WriteString("○")
+		default:
+			card.WriteString("-")
+		}
+	}
+	return card.String()
+}
+
+// CurrentStage возвращает последнюю стрельбу участника или nil
+func (a *Athlete) CurrentStage() *ShootingStage {
+	if len(a.Stages) == 0 {
+		return nil
+	}
+	return &a.Stages[len(a.Stages)-1]
+}
+
+// PositionShooting возвращает выстрелы и попадания во всех стрельбах в положении position
+func (a *Athlete) PositionShooting(position string) (shots, hits int) {
+	for _, stage := range a.Stages {
+		if stage.Position == position {
+			shots += stage.Shots
+			hits += stage.Hits
+		}
+	}
+	return shots, hits
+}
+
+// ShootingString записывает промахи по рубежам так, как принято в
+// протоколах биатлона: "1+0+2+0 = 3". Без стрельб возвращает пустую строку
+func ShootingString(stages []ShootingStage) string {
+	if len(stages) == 0 {
+		return ""
+	}
+	parts := make([]string, len(stages))
+	total := 0
+	for i, stage := range stages {
+		parts[i] = strconv.Itoa(stage.Misses())
+		total += stage.Misses()
+	}
+	return strings.Join(parts, "+") + " = " + strconv.Itoa(total)
+}
+
+// RelayShootingString записывает стрельбу в эстафете так, как принято в
+// протоколах: штрафные круги и запасные патроны по рубежам, "0+1 1+3".
+// Без стрельб возвращает пустую строку
+func RelayShootingString(stages []ShootingStage) string {
+	parts := make([]string, len(stages))
+	for i, stage := range stages {
+		parts[i] = strconv.Itoa(stage.LoopsOwed) + "+" + strconv.Itoa(stage.Spares)
+	}
+	return strings.Join(parts, " ")
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"biathlon-prototype/race"
+	"biathlon-prototype/utils"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// pursuitEventsLead - за сколько до старта гонки преследования записываются
+// регистрация и жеребьевка участников
+const pursuitEventsLead = 30 * time.Minute
+
+func runPursuit(args []string) int {
+	var resultsPath, cutoff, lotteryPath string
+	var limit int
+	opts, err := parseOptions("pursuit", args, func(fs *flag.FlagSet) {
+		fs.StringVar(&resultsPath, "results", "", "протокол предыдущей гонки в JSON (report -format json)")
+		fs.IntVar(&limit, "limit", 60, "сколько лучших участников допускается, 0 - все")
+		fs.StringVar(&cutoff, "cutoff", "", "наибольшее отставание от победителя ЧЧ:ММ:СС, участники дальше не допускаются")
+		fs.StringVar(&lotteryPath, "lottery", "", "записать регистрацию (1) и жеребьевку (2) участников в файл событий")
+	})
+	if err != nil {
+		return exitCode(err)
+	}
+	if resultsPath == "" {
+		fmt.Fprintln(os.Stderr, "не указан протокол предыдущей гонки: -results")
+		return 2
+	}
+
+	rules := race.PursuitRules{Limit: limit}
+	if cutoff != "" {
+		if rules.Cutoff, err = utils.ParseDelta(cutoff); err != nil || rules.Cutoff < 0 {
+			fmt.Fprintf(os.Stderr, "некорректное отставание -cutoff %q, ожидается ЧЧ:ММ:СС\n", cutoff)
+			return 2
+		}
+	}
+
+	r, err := loadRace(opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	r.Output = io.Discard
+	if r.Format.Name() != race.FormatPursuit {
+		fmt.Fprintf(os.Stderr, "Внимание: формат гонки в конфигурации - %s, а не %s\n", r.Format.Name(), race.FormatPursuit)
+	}
+
+	input, err := os.Open(resultsPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Ошибка открытия протокола: %v\n", err)
+		return 1
+	}
+	defer input.Close()
+	previous, err := race.ReadResults(input)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", resultsPath, err)
+		return 1
+	}
+
+	list, excluded := r.PursuitStartList(previous, rules)
+	for _, problem := range excluded {
+		fmt.Fprintf(os.Stderr, "Не допущен: %s\n", problem)
+	}
+
+	// Протокол проходит через гонку теми же событиями, что записываются в файл
+	applied, err := r.ApplyStartList(list, r.StartTime.Add(-pursuitEventsLead))
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Ошибка применения стартового протокола: %v\n", err)
+		return 1
+	}
+
+	if err := writeStartList(os.Stdout, r, list, opts.format); err != nil {
+		fmt.Fprintf(os.Stderr, "Ошибка вывода стартового протокола: %v\n", err)
+		return 1
+	}
+	if lotteryPath != "" {
+		if err := writeEvents(lotteryPath, applied); err != nil {
+			fmt.Fprintf(os.Stderr, "Ошибка записи событий: %v\n", err)
+			return 1
+		}
+	}
+	return 0
+}
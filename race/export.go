@@ -0,0 +1,427 @@
+package race
+
+import (
+	"biathlon-prototype/configs"
+	"biathlon-prototype/models"
+	"biathlon-prototype/utils"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// RaceResults - итоговый протокол гонки для машинной обработки
+type RaceResults struct {
+	Format       string          `json:"format"`
+	TimeZone     string          `json:"timeZone"` // Пояс, в котором записаны plannedStart, actualStart и finish
+	RankingBasis string          `json:"rankingBasis"`
+	Laps         int             `json:"laps"`
+	LapLen       int             `json:"lapLen"`
+	PenaltyLen   int             `json:"penaltyLen"`
+	LapLens      []int           `json:"lapLens"`               // Длина каждого круга по профилю трассы
+	Stages       []string        `json:"stages,omitempty"`      // Положения на огневых рубежах по порядку
+	PenaltyLens  []int           `json:"penaltyLens,omitempty"` // Длины штрафных кругов по рубежам
+	Legs         int             `json:"legs,omitempty"`        // Этапов в эстафете
+	Teams        []TeamResult    `json:"teams,omitempty"`       // Итоги команд эстафеты в порядке TeamStandings
+	Results      []AthleteResult `json:"results"`
+}
+
+// TeamResult - результат команды эстафеты
+type TeamResult struct {
+	Position int         `json:"position"`
+	Tied     bool        `json:"tied,omitempty"`
+	TeamID   int         `json:"teamId"`
+	Team     string      `json:"team"`
+	Nation   string      `json:"nation,omitempty"`
+	Status   string      `json:"status"`
+	Time     string      `json:"time,omitempty"`
+	Gap      string      `json:"gap,omitempty"`
+	Legs     []LegResult `json:"legs"`
+}
+
+// LegResult - этап команды эстафеты
+type LegResult struct {
+	Leg          int    `json:"leg"`
+	AthleteID    int    `json:"athleteId"`
+	Status       string `json:"status,omitempty"` // Пусто, если участника этапа не было в событиях
+	Time         string `json:"time,omitempty"`   // Время этапа
+	Split        string `json:"split,omitempty"`  // От общего старта до конца этапа
+	PenaltyLoops int    `json:"penaltyLoops"`
+	Spares       int    `json:"spares"`
+	Shooting     string `json:"shooting,omitempty"` // Штрафные круги и запасные патроны по рубежам: "0+1 1+3"
+}
+
+// AthleteResult - результат одного участника. Времена записаны как
+// ЧЧ:ММ:СС.ммм, скорости - в м/с с точностью до сотых
+type AthleteResult struct {
+	Position      int             `json:"position"`
+	Tied          bool            `json:"tied,omitempty"`
+	AthleteID     int             `json:"athleteId"`
+	Entry         *models.Entry   `json:"entry,omitempty"` // Заявка из ростера, если он загружен
+	Status        string          `json:"status"`
+	PlannedStart  string          `json:"plannedStart,omitempty"`
+	ActualStart   string          `json:"actualStart,omitempty"`
+	Finish        string          `json:"finish,omitempty"`
+	NetTime       string          `json:"netTime,omitempty"`
+	OfficialTime  string          `json:"officialTime,omitempty"`
+	Gap           string          `json:"gap,omitempty"`
+	PhotoFinish   int             `json:"photoFinish,omitempty"` // Место по фотофинишу среди финишировавших одновременно
+	Laps          []SegmentResult `json:"laps"`
+	Penalties     []SegmentResult `json:"penalties"`
+	PenaltyLoops  string          `json:"penaltyLoopsTime"`
+	LoopsOwed     int             `json:"penaltyLoopsOwed"`
+	LoopsServed   int             `json:"penaltyLoopsServed"`
+	SkippedLoops  int             `json:"skippedLoops,omitempty"`   // Пропущенные штрафные круги
+	SuspectLoops  int             `json:"suspectedLoops,omitempty"` // Штрафные круги, пройденные подозрительно быстро
+	LoopPenalty   string          `json:"loopPenalty,omitempty"`    // Штрафное время за пропущенные круги
+	TimePenalty   string          `json:"timePenalty,omitempty"`
+	TotalDistance int             `json:"totalDistance"`
+	AvgSpeed      float64         `json:"avgSpeed"`
+	Shots         int             `json:"shots"`
+	Hits          int             `json:"hits"`
+	Accuracy      float64         `json:"accuracy"`
+	Misses        string          `json:"misses,omitempty"` // Промахи по рубежам "1+0+2+0 = 3", в эстафете - "0+1 1+3" (см. Race.ShootingString)
+	Shooting      []StageResult   `json:"shooting"`
+	Prone         *PositionResult `json:"prone,omitempty"`    // Стрельба лежа, если положения заданы в stages
+	Standing      *PositionResult `json:"standing,omitempty"` // Стрельба стоя
+}
+
+// StageResult - итог одной стрельбы участника
+type StageResult struct {
+	Stage     int    `json:"stage"`
+	Line      int    `json:"line"`
+	Lane      int    `json:"lane,omitempty"`
+	Position  string `json:"position,omitempty"`
+	Shots     int    `json:"shots"`
+	Hits      int    `json:"hits"`
+	Misses    int    `json:"misses"`
+	Targets   []int  `json:"targets"`          // Номера закрытых мишеней по возрастанию
+	Card      string `json:"card"`             // Карточка стрельбы, см. models.ShootingStage.TargetCard
+	Spares    int    `json:"spares,omitempty"` // Запасных патронов дозаряжено (эстафета)
+	RangeTime string `json:"rangeTime,omitempty"`
+}
+
+// PositionResult - точность стрельбы в одном положении
+type PositionResult struct {
+	Shots    int     `json:"shots"`
+	Hits     int     `json:"hits"`
+	Accuracy float64 `json:"accuracy"`
+}
+
+// SegmentResult - время и скорость на круге или штрафном отрезке
+type SegmentResult struct {
+	Number int     `json:"number"`
+	Time   string  `json:"time"`
+	Speed  float64 `json:"speed,omitempty"`
+}
+
+// Results собирает итоговый протокол в порядке Standings
+func (r *Race) Results() RaceResults {
+	results := RaceResults{
+		Format:       r.Format.Name(),
+		TimeZone:     r.ReportZone.String(),
+		RankingBasis: r.Format.RankingBasis(),
+		Laps:         r.Config.Laps,
+		LapLen:       r.Config.LapLen,
+		PenaltyLen:   r.Config.PenaltyLen,
+		LapLens:      make([]int, r.Config.Laps),
+		Stages:       r.Config.Stages,
+		PenaltyLens:  r.Config.PenaltyLens,
+		Results:      make([]AthleteResult, 0, len(r.Athletes)),
+	}
+	for i := range results.LapLens {
+		results.LapLens[i] = r.Config.LapLength(i + 1)
+	}
+	if r.Format.Name() == FormatRelay {
+		results.Legs = r.Legs
+		results.Teams = r.TeamResults()
+	}
+	for _, standing := range r.Standings() {
+		results.Results = append(results.Results, r.athleteResult(standing))
+	}
+	return results
+}
+
+// TeamResults собирает итоги команд эстафеты в порядке TeamStandings
+func (r *Race) TeamResults() []TeamResult {
+	standings := r.TeamStandings()
+	results := make([]TeamResult, len(standings))
+	for i, standing := range standings {
+		results[i] = teamResult(standing)
+	}
+	return results
+}
+
+func teamResult(standing TeamStanding) TeamResult {
+	result := TeamResult{
+		Position: standing.Rank,
+		Tied:     standing.Tied,
+		TeamID:   standing.Team.ID,
+		Team:     standing.Team.Name,
+		Nation:   standing.Team.Nation,
+		Status:   string(standing.Status),
+		Legs:     make([]LegResult, len(standing.Legs)),
+	}
+	if standing.Ranked {
+		result.Time = utils.FormatDuration(standing.Time)
+		result.Gap = utils.FormatDuration(standing.Gap)
+	}
+	for i, leg := range standing.Legs {
+		legResult := LegResult{
+			Leg:          leg.Leg,
+			AthleteID:    standing.Team.LegAthlete(leg.Leg),
+			PenaltyLoops: leg.Loops,
+			Spares:       leg.Spares,
+			Shooting:     leg.Shooting,
+		}
+		if leg.Athlete != nil {
+			legResult.Status = string(leg.Athlete.Status)
+		}
+		if leg.Done {
+			legResult.Time = utils.FormatDuration(leg.Time)
+			legResult.Split = utils.FormatDuration(leg.Split)
+		}
+		result.Legs[i] = legResult
+	}
+	return result
+}
+
+func (r *Race) athleteResult(standing Standing) AthleteResult {
+	a := standing.Athlete
+	result := AthleteResult{
+		Position:      standing.Rank,
+		Tied:          standing.Tied,
+		AthleteID:     a.ID,
+		Entry:         a.Entry,
+		PhotoFinish:   a.PhotoFinish,
+		Status:        string(a.Status),
+		Laps:          segments(a.LapTimes, func(i int) int { return r.Config.LapLength(i + 1) }),
+		Penalties:     segments(a.PenaltyTimes, func(i int) int { return r.penaltyLength(a, i) }),
+		TotalDistance: a.TotalDistance,
+		AvgSpeed:      round2(a.AvgSpeed),
+		Shots:         a.Shots,
+		Hits:          a.Hits,
+		Accuracy:      round2(a.Accuracy),
+		Misses:        standing.Shooting,
+		Shooting:      StageResults(a),
+		Prone:         positionResult(a, configs.PositionProne),
+		Standing:      positionResult(a, configs.PositionStanding),
+	}
+
+	if !a.StartTimePlanned.IsZero() {
+		result.PlannedStart = utils.FormatTime(r.ReportTime(a.StartTimePlanned))
+	}
+	if a.StartTimeActual != nil {
+		result.ActualStart = utils.FormatTime(r.ReportTime(*a.StartTimeActual))
+	}
+	if a.FinishTime != nil {
+		result.Finish = utils.FormatTime(r.ReportTime(*a.FinishTime))
+	}
+	if a.StartTimeActual != nil && a.FinishTime != nil {
+		result.NetTime = utils.FormatDuration(standing.NetTime)
+	}
+
+	if standing.Ranked {
+		result.OfficialTime = utils.FormatDuration(standing.OfficialTime)
+		result.Gap = utils.FormatDuration(standing.Gap)
+	}
+
+	result.PenaltyLoops = utils.FormatDuration(standing.PenaltyLoops)
+	result.LoopsOwed = standing.LoopsOwed
+	result.LoopsServed = standing.LoopsServed
+	result.SkippedLoops = standing.SkippedLoops
+	result.SuspectLoops = standing.Suspected
+	if standing.LoopPenalty > 0 {
+		result.LoopPenalty = utils.FormatDuration(standing.LoopPenalty)
+	}
+	if a.TimePenalty > 0 {
+		result.TimePenalty = utils.FormatDuration(a.TimePenalty)
+	}
+	return result
+}
+
+// StageResults переводит стрельбы участника в результаты
+func StageResults(a *models.Athlete) []StageResult {
+	result := make([]StageResult, 0, len(a.Stages))
+	for _, stage := range a.Stages {
+		stageResult := StageResult{
+			Stage:    stage.Stage,
+			Line:     stage.Line,
+			Lane:     stage.Lane,
+			Position: stage.Position,
+			Shots:    stage.Shots,
+			Hits:     stage.Hits,
+			Misses:   stage.Misses(),
+			Targets:  make([]int, 0, stage.Hits),
+			Card:     stage.TargetCard(),
+			Spares:   stage.Spares,
+		}
+		for target, hit := range stage.Targets {
+			if hit {
+				stageResult.Targets = append(stageResult.Targets, target)
+			}
+		}
+		sort.Ints(stageResult.Targets)
+		if rangeTime := stage.RangeTime(); rangeTime > 0 {
+			stageResult.RangeTime = utils.FormatDuration(rangeTime)
+		}
+		result = append(result, stageResult)
+	}
+	return result
+}
+
+// positionResult возвращает точность в положении position или nil,
+// если участник в нем не стрелял
+func positionResult(a *models.Athlete, position string) *PositionResult {
+	shots, hits := a.PositionShooting(position)
+	if shots == 0 {
+		return nil
+	}
+	return &PositionResult{
+		Shots:    shots,
+		Hits:     hits,
+		Accuracy: round2(float64(hits) / float64(shots) * 100),
+	}
+}
+
+// segments переводит времена отрезков в результаты; length возвращает
+// длину i-го (с 0) отрезка в метрах. Для нулевого времени скорость не считается
+func segments(times []time.Duration, length func(i int) int) []SegmentResult {
+	result := make([]SegmentResult, 0, len(times))
+	for i, t := range times {
+		segment := SegmentResult{Number: i + 1, Time: utils.FormatDuration(t)}
+		if t > 0 {
+			segment.Speed = round2(float64(length(i)) / t.Seconds())
+		}
+		result = append(result, segment)
+	}
+	return result
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+// WriteJSON выводит итоговый протокол в JSON с подробностями по кругам и штрафам
+func (r *Race) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r.Results())
+}
+
+// entryColumns возвращает колонки bib, name, nation, team, category
+// для заявки entry; без заявки колонки пустые
+func entryColumns(entry *models.Entry) []string {
+	if entry == nil {
+		return make([]string, 5)
+	}
+	bib := ""
+	if entry.Bib > 0 {
+		bib = strconv.Itoa(entry.Bib)
+	}
+	return []string{bib, entry.Name, entry.Nation, entry.Team, entry.Category}
+}
+
+// relayColumns - колонки эстафеты в CSV: итог команды участника и его этап
+var relayColumns = []string{"team_id", "team_position", "team_tied", "team_status", "team_time", "team_gap", "leg", "leg_time", "leg_split", "spares"}
+
+// teamColumns возвращает колонки relayColumns для участника athleteID
+// по итогам команд; если участник не заявлен в команду, колонки пустые
+func teamColumns(teams []TeamResult, athleteID int) []string {
+	for _, team := range teams {
+		for _, leg := range team.Legs {
+			if leg.AthleteID != athleteID {
+				continue
+			}
+			return []string{
+				strconv.Itoa(team.TeamID),
+				strconv.Itoa(team.Position),
+				strconv.FormatBool(team.Tied),
+				team.Status,
+				team.Time,
+				team.Gap,
+				strconv.Itoa(leg.Leg),
+				leg.Time,
+				leg.Split,
+				strconv.Itoa(leg.Spares),
+			}
+		}
+	}
+	return make([]string, len(relayColumns))
+}
+
+// WriteCSV выводит итоговый протокол в CSV: одна строка на участника,
+// по колонке на каждый круг из конфигурации. Места определяются
+// по official_time (см. Format.RankingBasis). В эстафете к строке
+// участника добавляются итог его команды и этап (relayColumns)
+func (r *Race) WriteCSV(w io.Writer) error {
+	results := r.Results()
+	relay := r.Format.Name() == FormatRelay
+
+	header := []string{"position", "tied", "athlete_id", "bib", "name", "nation", "team", "category", "status", "planned_start", "actual_start", "finish", "net_time", "official_time", "gap"}
+	for lap := 1; lap <= results.Laps; lap++ {
+		header = append(header, fmt.Sprintf("lap_%d", lap))
+	}
+	header = append(header, "penalty_loops_time", "penalty_loops_owed", "penalty_loops_served", "skipped_loops", "suspected_loops", "loop_penalty", "time_penalty", "total_distance", "avg_speed", "shots", "hits", "accuracy", "misses")
+	if relay {
+		header = append(header, relayColumns...)
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+
+	for _, res := range results.Results {
+		row := []string{
+			strconv.Itoa(res.Position),
+			strconv.FormatBool(res.Tied),
+			strconv.Itoa(res.AthleteID),
+		}
+		row = append(row, entryColumns(res.Entry)...)
+		row = append(row,
+			res.Status,
+			res.PlannedStart,
+			res.ActualStart,
+			res.Finish,
+			res.NetTime,
+			res.OfficialTime,
+			res.Gap,
+		)
+		for lap := 0; lap < results.Laps; lap++ {
+			if lap < len(res.Laps) {
+				row = append(row, res.Laps[lap].Time)
+			} else {
+				row = append(row, "")
+			}
+		}
+		row = append(row,
+			res.PenaltyLoops,
+			strconv.Itoa(res.LoopsOwed),
+			strconv.Itoa(res.LoopsServed),
+			strconv.Itoa(res.SkippedLoops),
+			strconv.Itoa(res.SuspectLoops),
+			res.LoopPenalty,
+			res.TimePenalty,
+			strconv.Itoa(res.TotalDistance),
+			strconv.FormatFloat(res.AvgSpeed, 'f', 2, 64),
+			strconv.Itoa(res.Shots),
+			strconv.Itoa(res.Hits),
+			strconv.FormatFloat(res.Accuracy, 'f', 2, 64),
+			res.Misses,
+		)
+		if relay {
+			row = append(row, teamColumns(results.Teams, res.AthleteID)...)
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,94 @@
+package race
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"testing"
+)
+
+func TestWriteJSON(t *testing.T) {
+	r := createTestRaceWithAthletes()
+
+	var buf bytes.Buffer
+	if err := r.WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON() error = %v", err)
+	}
+
+	var got RaceResults
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("WriteJSON() produced invalid JSON: %v", err)
+	}
+
+	if got.Format != FormatSprint || got.Laps != 2 {
+		t.Errorf("Header = %s/%d, want sprint/2", got.Format, got.Laps)
+	}
+	if len(got.Results) != 3 {
+		t.Fatalf("Expected 3 results, got %d", len(got.Results))
+	}
+
+	first := got.Results[0]
+	if first.Position != 1 || first.AthleteID != 1 {
+		t.Errorf("First result = #%d athlete %d, want #1 athlete 1", first.Position, first.AthleteID)
+	}
+	if first.NetTime != "01:30:00.000" || first.OfficialTime != "01:30:00.000" {
+		t.Errorf("NetTime/OfficialTime = %s/%s, want 01:30:00.000", first.NetTime, first.OfficialTime)
+	}
+	if len(first.Laps) != 2 || first.Laps[0].Time != "00:30:00.000" || first.Laps[0].Speed != 2.22 {
+		t.Errorf("Laps = %+v, want 2 laps of 00:30:00.000 at 2.22 m/s", first.Laps)
+	}
+	if len(first.Penalties) != 1 || first.Penalties[0].Speed != 1.25 {
+		t.Errorf("Penalties = %+v, want 1 penalty at 1.25 m/s", first.Penalties)
+	}
+	if first.Accuracy != 80 {
+		t.Errorf("Accuracy = %.2f, want 80", first.Accuracy)
+	}
+	if first.Misses != "1+1 = 2" {
+		t.Errorf("Misses = %q, want \"1+1 = 2\"", first.Misses)
+	}
+
+	last := got.Results[2]
+	if last.AthleteID != 3 || last.Status != "Disqualified" || last.OfficialTime != "" {
+		t.Errorf("Last result = %+v, want disqualified athlete 3 without time", last)
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	r := createTestRaceWithAthletes()
+
+	var buf bytes.Buffer
+	if err := r.WriteCSV(&buf); err != nil {
+		t.Fatalf("WriteCSV() error = %v", err)
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("WriteCSV() produced invalid CSV: %v", err)
+	}
+
+	// Заголовок и по строке на участника
+	if len(records) != 4 {
+		t.Fatalf("Expected 4 records, got %d", len(records))
+	}
+
+	header := records[0]
+	column := make(map[string]int, len(header))
+	for i, name := range header {
+		column[name] = i
+	}
+	for _, name := range []string{"position", "athlete_id", "bib", "name", "lap_1", "lap_2", "net_time", "official_time", "accuracy", "misses"} {
+		if _, ok := column[name]; !ok {
+			t.Errorf("Expected column %q in header %v", name, header)
+		}
+	}
+
+	second := records[2]
+	if second[column["athlete_id"]] != "2" || second[column["lap_2"]] != "00:32:00.000" || second[column["misses"]] != "2+1 = 3" {
+		t.Errorf("Unexpected second row %v", second)
+	}
+
+	disqualified := records[3]
+	if disqualified[column["status"]] != "Disqualified" || disqualified[column["lap_1"]] != "" {
+		t.Errorf("Unexpected disqualified row %v", disqualified)
+	}
+}
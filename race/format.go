@@ -0,0 +1,239 @@
+package race
+
+import (
+	"biathlon-prototype/models"
+	"fmt"
+	"time"
+)
+
+// Названия форматов гонки, как они задаются в конфигурации
+const (
+	FormatSprint     = "sprint"
+	FormatIndividual = "individual"
+	FormatPursuit    = "pursuit"
+	FormatMassStart  = "mass-start"
+	FormatRelay      = "relay"
+)
+
+// IndividualMissPenalty - штрафное время за каждый промах в индивидуальной гонке
+const IndividualMissPenalty = time.Minute
+
+// Format описывает правила конкретного вида гонки: как определяется время
+// старта, чем наказывается промах и от какого момента считается
+// официальное время, по которому ранжируются участники
+type Format interface {
+	// Name возвращает название формата
+	Name() string
+	// PlannedStart возвращает время, к которому участник должен стартовать.
+	// Нулевое время означает, что старт еще не назначен
+	PlannedStart(r *Race, a *models.Athlete) time.Time
+	// Penalize применяет штраф за один промах
+	Penalize(a *models.Athlete)
+	// SettleStage подводит итог текущей стрельбы, когда участник уходит с рубежа
+	SettleStage(a *models.Athlete)
+	// OfficialStart возвращает момент, от которого отсчитывается
+	// официальное время участника
+	OfficialStart(r *Race, a *models.Athlete) time.Time
+	// RankingBasis описывает для протокола, по какому времени ранжируются участники
+	RankingBasis() string
+	// StartMode возвращает, как стартуют участники
+	StartMode() StartMode
+}
+
+// StartMode - способ старта участников
+type StartMode string
+
+const (
+	StartInterval StartMode = "interval" // По одному через StartDelta от Config.Start, порядок по жеребьевке
+	StartHandicap StartMode = "handicap" // С гандикапом по итогам предыдущей гонки, время приходит жеребьевкой
+	StartCommon   StartMode = "common"   // Все вместе в Config.Start, жеребьевка не нужна
+)
+
+// formats - все форматы гонки в порядке configs.Formats
+var formats = []Format{sprintFormat{}, individualFormat{}, pursuitFormat{}, massStartFormat{}, relayFormat{}}
+
+// FormatByName возвращает формат гонки по названию.
+// Пустое название означает спринт - формат, в котором работал прототип
+func FormatByName(name string) (Format, error) {
+	if name == "" {
+		return sprintFormat{}, nil
+	}
+	for _, format := range formats {
+		if format.Name() == name {
+			return format, nil
+		}
+	}
+	return nil, fmt.Errorf("неизвестный формат гонки: %q", name)
+}
+
+// sprintFormat - раздельный старт по жеребьевке, штрафной круг за каждый промах,
+// ранжирование по времени прохождения дистанции
+type sprintFormat struct{}
+
+func (sprintFormat) Name() string { return FormatSprint }
+
+func (sprintFormat) PlannedStart(_ *Race, a *models.Athlete) time.Time {
+	return a.StartTimePlanned
+}
+
+func (sprintFormat) Penalize(a *models.Athlete) {
+	// Штрафной круг за каждый промах
+	oweLoop(a)
+}
+
+func (sprintFormat) SettleStage(*models.Athlete) {}
+
+func (sprintFormat) OfficialStart(_ *Race, a *models.Athlete) time.Time {
+	return plannedOrActualStart(a)
+}
+
+func (sprintFormat) RankingBasis() string {
+	return "официальное время: от планового старта до финиша"
+}
+
+func (sprintFormat) StartMode() StartMode { return StartInterval }
+
+// individualFormat - раздельный старт, вместо штрафных кругов
+// к результату добавляется фиксированное время за каждый промах
+type individualFormat struct{}
+
+func (individualFormat) Name() string { return FormatIndividual }
+
+func (individualFormat) PlannedStart(_ *Race, a *models.Athlete) time.Time {
+	return a.StartTimePlanned
+}
+
+func (individualFormat) Penalize(a *models.Athlete) {
+	a.TimePenalty += IndividualMissPenalty
+}
+
+func (individualFormat) SettleStage(*models.Athlete) {}
+
+func (individualFormat) OfficialStart(_ *Race, a *models.Athlete) time.Time {
+	return plannedOrActualStart(a)
+}
+
+func (individualFormat) RankingBasis() string {
+	return "официальное время: от планового старта до финиша плюс 1 минута за каждый промах"
+}
+
+func (individualFormat) StartMode() StartMode { return StartInterval }
+
+// pursuitFormat - старт с гандикапом по отставанию в предыдущей гонке
+// (время старта приходит жеребьевкой), штрафные круги, ранжирование по порядку финиша
+type pursuitFormat struct{}
+
+func (pursuitFormat) Name() string { return FormatPursuit }
+
+func (pursuitFormat) PlannedStart(_ *Race, a *models.Athlete) time.Time {
+	return a.StartTimePlanned
+}
+
+func (pursuitFormat) Penalize(a *models.Athlete) {
+	oweLoop(a)
+}
+
+func (pursuitFormat) SettleStage(*models.Athlete) {}
+
+// В гонке преследования время считается от старта лидера, поэтому
+// стартовый гандикап входит в результат и места совпадают с порядком финиша
+func (pursuitFormat) OfficialStart(r *Race, _ *models.Athlete) time.Time {
+	return r.StartTime
+}
+
+func (pursuitFormat) RankingBasis() string {
+	return "официальное время: от старта лидера до финиша (порядок финиша)"
+}
+
+func (pursuitFormat) StartMode() StartMode { return StartHandicap }
+
+// massStartFormat - общий старт всех участников в Config.Start,
+// штрафные круги, ранжирование по порядку финиша
+type massStartFormat struct{}
+
+func (massStartFormat) Name() string { return FormatMassStart }
+
+func (massStartFormat) PlannedStart(r *Race, _ *models.Athlete) time.Time {
+	return r.StartTime
+}
+
+func (massStartFormat) Penalize(a *models.Athlete) {
+	oweLoop(a)
+}
+
+func (massStartFormat) SettleStage(*models.Athlete) {}
+
+func (massStartFormat) OfficialStart(r *Race, _ *models.Athlete) time.Time {
+	return r.StartTime
+}
+
+func (massStartFormat) RankingBasis() string {
+	return "официальное время: от общего старта до финиша (порядок финиша)"
+}
+
+func (massStartFormat) StartMode() StartMode { return StartCommon }
+
+// relayFormat - эстафета: первый этап стартует вместе в Config.Start,
+// следующие - с передачи эстафеты (событие 12). Промахи сначала закрываются
+// запасными патронами, штрафные круги - за мишени, оставшиеся открытыми
+type relayFormat struct{}
+
+func (relayFormat) Name() string { return FormatRelay }
+
+// Время старта известно заранее только на первом этапе
+func (relayFormat) PlannedStart(r *Race, a *models.Athlete) time.Time {
+	if relayLeg(a) > 1 {
+		return time.Time{}
+	}
+	return r.StartTime
+}
+
+// Промах еще можно закрыть запасным патроном, круги считаются в SettleStage
+func (relayFormat) Penalize(*models.Athlete) {}
+
+func (relayFormat) SettleStage(a *models.Athlete) {
+	if stage := a.CurrentStage(); stage != nil {
+		stage.LoopsOwed = models.TargetsPerStage - stage.Hits
+	}
+}
+
+// Время этапа - от общего старта или приема эстафеты до передачи или финиша
+func (relayFormat) OfficialStart(r *Race, a *models.Athlete) time.Time {
+	if relayLeg(a) > 1 {
+		if a.StartTimeActual == nil {
+			return time.Time{}
+		}
+		return *a.StartTimeActual
+	}
+	return r.StartTime
+}
+
+func (relayFormat) RankingBasis() string {
+	return "время команды: от общего старта до финиша последнего этапа (порядок финиша); участники - по времени этапа"
+}
+
+func (relayFormat) StartMode() StartMode { return StartCommon }
+
+// relayLeg возвращает этап эстафеты участника по заявке или 0, если он неизвестен
+func relayLeg(a *models.Athlete) int {
+	if a.Entry == nil {
+		return 0
+	}
+	return a.Entry.Leg
+}
+
+// plannedOrActualStart возвращает плановое время старта, а если жеребьевки
+// не было - фактическое
+func plannedOrActualStart(a *models.Athlete) time.Time {
+	if a.StartTimePlanned.IsZero() && a.StartTimeActual != nil {
+		return *a.StartTimeActual
+	}
+	return a.StartTimePlanned
+}
+
+// oweLoop добавляет штрафной круг к долгу текущей стрельбы участника
+func oweLoop(a *models.Athlete) {
+	if stage := a.CurrentStage(); stage != nil {
+		stage.LoopsOwed++
+	}
+}
@@ -0,0 +1,283 @@
+package race
+
+import (
+	"biathlon-prototype/configs"
+	"biathlon-prototype/events"
+	"biathlon-prototype/models"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestFormatByName(t *testing.T) {
+	testCases := []struct {
+		name     string
+		wantName string
+		wantErr  bool
+	}{
+		{name: "", wantName: FormatSprint},
+		{name: "sprint", wantName: FormatSprint},
+		{name: "individual", wantName: FormatIndividual},
+		{name: "pursuit", wantName: FormatPursuit},
+		{name: "mass-start", wantName: FormatMassStart},
+		{name: "relay", wantName: FormatRelay},
+		{name: "team-sprint", wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			format, err := FormatByName(tc.name)
+			if tc.wantErr {
+				if err == nil {
+					t.Errorf("FormatByName(%q) expected error, got nil", tc.name)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("FormatByName(%q) unexpected error = %v", tc.name, err)
+			}
+			if format.Name() != tc.wantName {
+				t.Errorf("FormatByName(%q) = %s, want %s", tc.name, format.Name(), tc.wantName)
+			}
+		})
+	}
+}
+
+// Конфигурация проверяет формат по своему списку, он должен совпадать с форматами гонки
+func TestFormats_MatchConfig(t *testing.T) {
+	if len(formats) != len(configs.Formats) {
+		t.Fatalf("race formats = %d, configs.Formats = %d", len(formats), len(configs.Formats))
+	}
+	for i, format := range formats {
+		if format.Name() != configs.Formats[i] {
+			t.Errorf("Format %d = %q, configs.Formats has %q", i, format.Name(), configs.Formats[i])
+		}
+	}
+}
+
+func TestNewRace_UnknownFormat(t *testing.T) {
+	cfg := configs.Config{
+		Laps:       2,
+		Start:      "10:00:00",
+		StartDelta: "00:01:00",
+		Format:     "team-sprint",
+	}
+
+	if _, err := NewRace(cfg); err == nil {
+		t.Error("Expected error for unknown format, got nil")
+	}
+}
+
+func TestIndividual_MissAddsTimePenalty(t *testing.T) {
+	r := createTestRaceWithFormat(FormatIndividual)
+	registerAndStartAthlete(r, 1)
+
+	r.HandleEvent(createTestEvent(events.EventAtFiringLine, "10:30:00.000", 1, "1"))
+	r.HandleEvent(createTestEvent(events.EventHitMissed, "10:30:05.000", 1, "1"))
+	r.HandleEvent(createTestEvent(events.EventHitMissed, "10:30:06.000", 1, "2"))
+
+	athlete := r.Athletes[1]
+	if athlete.TimePenalty != 2*IndividualMissPenalty {
+		t.Errorf("Expected time penalty %v, got %v", 2*IndividualMissPenalty, athlete.TimePenalty)
+	}
+
+	if len(athlete.PenaltyTimes) != 0 {
+		t.Errorf("Expected no penalty loops, got %d", len(athlete.PenaltyTimes))
+	}
+}
+
+func TestIndividual_RankingIncludesTimePenalty(t *testing.T) {
+	r := createTestRaceWithFormat(FormatIndividual)
+	start := time.Date(0, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	// Участник 1 быстрее на дистанции, но получил 2 минуты штрафа
+	r.Athletes[1] = &models.Athlete{
+		ID:              1,
+		Status:          models.StatusFinished,
+		StartTimeActual: timePtr(start),
+		FinishTime:      timePtr(start.Add(50 * time.Minute)),
+		TimePenalty:     2 * time.Minute,
+	}
+	r.Athletes[2] = &models.Athlete{
+		ID:              2,
+		Status:          models.StatusFinished,
+		StartTimeActual: timePtr(start),
+		FinishTime:      timePtr(start.Add(51 * time.Minute)),
+	}
+
+	results := r.Standings()
+	if results[0].Athlete.ID != 2 {
+		t.Errorf("Expected athlete 2 to win, got %d", results[0].Athlete.ID)
+	}
+}
+
+func TestMassStart_PlannedStartIsCommon(t *testing.T) {
+	r := createTestRaceWithFormat(FormatMassStart)
+	registerAthlete(r, 1)
+
+	// Без жеребьевки участник должен стартовать в общее время старта
+	athlete := r.Athletes[1]
+	if planned := r.Format.PlannedStart(r, athlete); !planned.Equal(r.StartTime) {
+		t.Errorf("Expected planned start %v, got %v", r.StartTime, planned)
+	}
+
+	// Событие позже общего старта + StartDelta для нестартовавшего - дисквалификация
+	r.HandleEvent(createTestEvent(events.EventRegister, "10:05:00.000", 2))
+	if status := r.Athletes[2].Status; status != models.StatusDisqualified {
+		t.Errorf("Expected status Disqualified, got %v", status)
+	}
+}
+
+func TestMassStart_NoLottery(t *testing.T) {
+	r := createTestRaceWithFormat(FormatMassStart)
+	r.Output = io.Discard
+	registerAthlete(r, 1)
+
+	err := r.HandleEvent(createTestEvent(events.EventStartTimeLottery, "09:05:00.000", 1, "10:01:00.000"))
+	if v, ok := err.(Violation); !ok || v.Kind != ViolationOrder {
+		t.Fatalf("HandleEvent(lottery) error = %v, want order violation", err)
+	}
+	if state := r.Athletes[1].State; state != models.StateScheduled {
+		t.Errorf("State = %s, want Scheduled without lottery", state)
+	}
+}
+
+func TestMassStart_LateStartUsesCommonStart(t *testing.T) {
+	r := createTestRaceWithFormat(FormatMassStart)
+	r.Output = io.Discard
+	for _, e := range []events.Event{
+		createTestEvent(events.EventRegister, "09:00:00.000", 1),
+		createTestEvent(events.EventRegister, "09:00:00.000", 2),
+		createTestEvent(events.EventRegister, "09:00:00.000", 3),
+		createTestEvent(events.EventAtStartLine, "09:59:00.000", 1),
+		createTestEvent(events.EventAtStartLine, "09:59:00.000", 2), // На линии, но не стартовал
+		createTestEvent(events.EventStart, "10:00:00.000", 1),
+		createTestEvent(events.EventAtFiringLine, "10:10:00.000", 1, "1"),
+	} {
+		if err := r.HandleEvent(e); err != nil {
+			t.Fatalf("HandleEvent() unexpected error = %v", err)
+		}
+	}
+
+	// Опоздавших дисквалифицирует событие другого участника после общего старта + StartDelta
+	if status := r.Athletes[1].Status; status != models.StatusRacing {
+		t.Errorf("Athlete 1 status = %s, want Racing", status)
+	}
+	for _, id := range []int{2, 3} {
+		if status := r.Athletes[id].Status; status != models.StatusDisqualified {
+			t.Errorf("Athlete %d status = %s, want Disqualified", id, status)
+		}
+	}
+}
+
+func TestMassStart_AssignedLanes(t *testing.T) {
+	r := createTestRaceWithFormat(FormatMassStart)
+	r.Output = io.Discard
+	r.SetRoster([]models.Entry{{ID: 1, Bib: 7, Name: "A"}, {ID: 2, Bib: 3, Name: "B"}})
+	for _, id := range []int{1, 2} {
+		r.HandleEvent(createTestEvent(events.EventRegister, "09:00:00.000", id))
+		r.HandleEvent(createTestEvent(events.EventAtStartLine, "09:59:00.000", id))
+		r.HandleEvent(createTestEvent(events.EventStart, "10:00:00.000", id))
+	}
+	for _, e := range []events.Event{
+		createTestEvent(events.EventAtFiringLine, "10:10:00.000", 1, "1"),
+		createTestEvent(events.EventAtFiringLine, "10:10:05.000", 2, "1", "12"), // Позиция указана явно
+		createTestEvent(events.EventLeaveFiringLine, "10:10:30.000", 2),
+		createTestEvent(events.EventLeaveFiringLine, "10:10:40.000", 1),
+		createTestEvent(events.EventLapFinish, "10:20:00.000", 2),
+		createTestEvent(events.EventLapFinish, "10:20:10.000", 1),
+		createTestEvent(events.EventAtFiringLine, "10:30:00.000", 2, "1"),
+		createTestEvent(events.EventAtFiringLine, "10:30:10.000", 1, "1"),
+	} {
+		if err := r.HandleEvent(e); err != nil {
+			t.Fatalf("HandleEvent() unexpected error = %v", err)
+		}
+	}
+
+	// Первая стрельба - по нагрудному номеру, следующие - по порядку прихода
+	want := map[int][]int{1: {7, 2}, 2: {12, 1}}
+	for id, lanes := range want {
+		for i, stage := range r.Athletes[id].Stages {
+			if stage.Lane != lanes[i] {
+				t.Errorf("Athlete %d stage %d lane = %d, want %d", id, stage.Stage, stage.Lane, lanes[i])
+			}
+		}
+	}
+}
+
+func TestMassStart_PhotoFinish(t *testing.T) {
+	finish := time.Date(0, 1, 1, 10, 40, 0, 0, time.UTC)
+	newAthlete := func(id, photo int) *models.Athlete {
+		return &models.Athlete{
+			ID:              id,
+			Status:          models.StatusFinished,
+			StartTimeActual: timePtr(time.Date(0, 1, 1, 10, 0, 0, 0, time.UTC)),
+			FinishTime:      timePtr(finish),
+			PhotoFinish:     photo,
+		}
+	}
+
+	testCases := []struct {
+		name     string
+		format   string
+		photos   [3]int
+		wantIDs  [3]int
+		wantRank [3]int
+	}{
+		// Фотофиниш разводит одновременно финишировавших
+		{name: "Photo finish", format: FormatMassStart, photos: [3]int{3, 1, 2}, wantIDs: [3]int{2, 3, 1}, wantRank: [3]int{1, 2, 3}},
+		// Без фотофиниша участники делят место
+		{name: "No photo finish", format: FormatMassStart, wantIDs: [3]int{1, 2, 3}, wantRank: [3]int{1, 1, 1}},
+		// При раздельном старте одинаковое время - всегда дележ места
+		{name: "Interval start ignores photo", format: FormatSprint, photos: [3]int{3, 1, 2}, wantIDs: [3]int{1, 2, 3}, wantRank: [3]int{1, 1, 1}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := createTestRaceWithFormat(tc.format)
+			for i, photo := range tc.photos {
+				r.Athletes[i+1] = newAthlete(i+1, photo)
+				// Для раздельного старта время считается от планового старта
+				r.Athletes[i+1].StartTimePlanned = r.StartTime
+			}
+
+			standings := r.Standings()
+			for i := range tc.wantIDs {
+				if standings[i].Athlete.ID != tc.wantIDs[i] || standings[i].Rank != tc.wantRank[i] {
+					t.Errorf("standings[%d] = athlete %d rank %d, want athlete %d rank %d",
+						i, standings[i].Athlete.ID, standings[i].Rank, tc.wantIDs[i], tc.wantRank[i])
+				}
+			}
+		})
+	}
+}
+
+func TestPursuit_RankingByFinishOrder(t *testing.T) {
+	r := createTestRaceWithFormat(FormatPursuit)
+
+	// Участник 1 стартовал раньше и прошел дистанцию медленнее, но финишировал первым
+	r.Athletes[1] = &models.Athlete{
+		ID:              1,
+		Status:          models.StatusFinished,
+		StartTimeActual: timePtr(time.Date(0, 1, 1, 10, 0, 0, 0, time.UTC)),
+		FinishTime:      timePtr(time.Date(0, 1, 1, 10, 40, 0, 0, time.UTC)),
+	}
+	r.Athletes[2] = &models.Athlete{
+		ID:              2,
+		Status:          models.StatusFinished,
+		StartTimeActual: timePtr(time.Date(0, 1, 1, 10, 2, 0, 0, time.UTC)),
+		FinishTime:      timePtr(time.Date(0, 1, 1, 10, 40, 30, 0, time.UTC)),
+	}
+
+	results := r.Standings()
+	if results[0].Athlete.ID != 1 {
+		t.Errorf("Expected athlete 1 to win, got %d", results[0].Athlete.ID)
+	}
+}
+
+func createTestRaceWithFormat(format string) *Race {
+	r := createTestRace()
+	r.Config.Format = format
+	r.Format, _ = FormatByName(format)
+	return r
+}
@@ -0,0 +1,100 @@
+package race
+
+import (
+	"biathlon-prototype/events"
+	"biathlon-prototype/utils"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// ReadResults читает итоговый протокол гонки в формате WriteJSON
+func ReadResults(input io.Reader) (RaceResults, error) {
+	var results RaceResults
+	if err := json.NewDecoder(input).Decode(&results); err != nil {
+		return results, fmt.Errorf("ошибка чтения протокола: %v", err)
+	}
+	return results, nil
+}
+
+// PursuitRules - отбор участников в гонку преследования по протоколу предыдущей гонки
+type PursuitRules struct {
+	Limit  int           // Сколько лучших участников допускается, 0 - без ограничения
+	Cutoff time.Duration // Наибольшее допустимое отставание от победителя, 0 - без ограничения
+}
+
+// PursuitStartList строит стартовый протокол гонки преследования: участник
+// стартует через столько времени после Config.Start, сколько проиграл
+// победителю предыдущей гонки (поле gap протокола). Допускаются только
+// участники с официальным временем в пределах rules; остальные
+// возвращаются с причиной, по которой не допущены
+func (r *Race) PursuitStartList(previous RaceResults, rules PursuitRules) ([]StartListEntry, []StartListProblem) {
+	ranked := make([]AthleteResult, 0, len(previous.Results))
+	var excluded []StartListProblem
+	for _, result := range previous.Results {
+		if result.OfficialTime == "" {
+			excluded = append(excluded, StartListProblem{result.AthleteID,
+				fmt.Sprintf("нет официального времени в предыдущей гонке (статус %s)", result.Status)})
+			continue
+		}
+		ranked = append(ranked, result)
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].Position < ranked[j].Position
+	})
+
+	var list []StartListEntry
+	for _, result := range ranked {
+		gap, err := utils.ParseDuration(result.Gap)
+		if err != nil {
+			excluded = append(excluded, StartListProblem{result.AthleteID, fmt.Sprintf("отставание: %v", err)})
+			continue
+		}
+		if rules.Limit > 0 && result.Position > rules.Limit {
+			excluded = append(excluded, StartListProblem{result.AthleteID,
+				fmt.Sprintf("%d место, допускаются %d лучших", result.Position, rules.Limit)})
+			continue
+		}
+		if rules.Cutoff > 0 && gap > rules.Cutoff {
+			excluded = append(excluded, StartListProblem{result.AthleteID,
+				fmt.Sprintf("отставание %s больше допустимого %s", result.Gap, utils.FormatDuration(rules.Cutoff))})
+			continue
+		}
+		list = append(list, StartListEntry{
+			Order:     len(list) + 1,
+			AthleteID: result.AthleteID,
+			Entry:     result.Entry,
+			Start:     r.StartTime.Add(gap),
+		})
+	}
+	return list, excluded
+}
+
+// ApplyStartList передает гонке стартовый протокол событиями со временем at:
+// регистрацией (1) для еще не известных гонке участников и жеребьевкой (2).
+// Заявки из протокола переносятся участникам, у которых их еще нет.
+// Возвращает примененные события
+func (r *Race) ApplyStartList(list []StartListEntry, at time.Time) ([]events.Event, error) {
+	var applied []events.Event
+	for _, entry := range list {
+		if _, exists := r.Athletes[entry.AthleteID]; !exists {
+			register := events.Event{Time: at, EventID: events.EventRegister, AthleteID: entry.AthleteID}
+			if err := r.HandleEvent(register); err != nil {
+				return applied, err
+			}
+			applied = append(applied, register)
+		}
+		if athlete := r.Athletes[entry.AthleteID]; athlete.Entry == nil && entry.Entry != nil {
+			athlete.Entry = entry.Entry
+		}
+	}
+	for _, lottery := range LotteryEvents(list, at) {
+		if err := r.HandleEvent(lottery); err != nil {
+			return applied, err
+		}
+		applied = append(applied, lottery)
+	}
+	return applied, nil
+}
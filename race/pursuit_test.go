@@ -0,0 +1,123 @@
+package race
+
+import (
+	"biathlon-prototype/events"
+	"biathlon-prototype/models"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func pursuitTestRace(t *testing.T) *Race {
+	t.Helper()
+	cfg := createTestRace().Config
+	cfg.Format = FormatPursuit
+	r, err := NewRace(cfg)
+	if err != nil {
+		t.Fatalf("NewRace() error = %v", err)
+	}
+	r.Output = io.Discard
+	return r
+}
+
+func TestPursuitStartList(t *testing.T) {
+	previous := RaceResults{Results: []AthleteResult{
+		{Position: 1, AthleteID: 4, Status: "Finished", OfficialTime: "00:24:10.000", Gap: "00:00:00.000",
+			Entry: &models.Entry{Bib: 4, Name: "Johannes Boe"}},
+		{Position: 2, Tied: true, AthleteID: 2, Status: "Finished", OfficialTime: "00:24:35.500", Gap: "00:00:25.500"},
+		{Position: 2, Tied: true, AthleteID: 9, Status: "Finished", OfficialTime: "00:24:35.500", Gap: "00:00:25.500"},
+		{Position: 4, AthleteID: 1, Status: "Finished", OfficialTime: "00:27:20.000", Gap: "00:03:10.000"},
+		{Position: 5, AthleteID: 3, Status: "Finished", OfficialTime: "00:28:00.000", Gap: "00:03:50.000"},
+		{Position: 6, AthleteID: 5, Status: "Disqualified"},
+	}}
+
+	r := pursuitTestRace(t)
+	list, excluded := r.PursuitStartList(previous, PursuitRules{Limit: 4, Cutoff: 3 * time.Minute})
+
+	want := []struct {
+		athleteID int
+		gap       time.Duration
+	}{
+		{4, 0},
+		{2, 25500 * time.Millisecond},
+		{9, 25500 * time.Millisecond}, // Одинаковое отставание - одновременный старт
+	}
+	if len(list) != len(want) {
+		t.Fatalf("PursuitStartList() = %v, want %d athletes", list, len(want))
+	}
+	for i, w := range want {
+		if list[i].Order != i+1 || list[i].AthleteID != w.athleteID || !list[i].Start.Equal(r.StartTime.Add(w.gap)) {
+			t.Errorf("list[%d] = %+v, want athlete %d at start + %v", i, list[i], w.athleteID, w.gap)
+		}
+	}
+	if list[0].Entry == nil || list[0].Entry.Name != "Johannes Boe" {
+		t.Errorf("Entry of the leader = %+v, want carried from results", list[0].Entry)
+	}
+
+	wantExcluded := map[int]string{5: "нет официального времени", 1: "больше допустимого", 3: "допускаются 4 лучших"}
+	if len(excluded) != len(wantExcluded) {
+		t.Fatalf("Excluded = %v, want %d", excluded, len(wantExcluded))
+	}
+	for _, problem := range excluded {
+		if !strings.Contains(problem.Message, wantExcluded[problem.AthleteID]) {
+			t.Errorf("Excluded %v, want reason containing %q", problem, wantExcluded[problem.AthleteID])
+		}
+	}
+}
+
+func TestApplyStartList(t *testing.T) {
+	r := pursuitTestRace(t)
+	list := []StartListEntry{
+		{Order: 1, AthleteID: 4, Start: r.StartTime, Entry: &models.Entry{Bib: 4, Name: "Johannes Boe"}},
+		{Order: 2, AthleteID: 2, Start: r.StartTime.Add(25 * time.Second)},
+	}
+
+	applied, err := r.ApplyStartList(list, r.StartTime.Add(-30*time.Minute))
+	if err != nil {
+		t.Fatalf("ApplyStartList() error = %v", err)
+	}
+	ids := make([]int, len(applied))
+	for i, event := range applied {
+		ids[i] = event.EventID
+	}
+	if len(applied) != 4 || applied[0].EventID != events.EventRegister || applied[3].EventID != events.EventStartTimeLottery {
+		t.Errorf("Applied events = %v, want 2 registrations then 2 lottery events", ids)
+	}
+
+	athlete := r.Athletes[2]
+	if athlete.State != models.StateScheduled || !athlete.StartTimePlanned.Equal(r.StartTime.Add(25*time.Second)) {
+		t.Errorf("Athlete 2 = %s at %v, want scheduled at start + 25s", athlete.State, athlete.StartTimePlanned)
+	}
+	if r.Athletes[4].Entry == nil {
+		t.Error("Athlete 4 has no entry, want carried from the start list")
+	}
+
+	// Итоговое время в преследовании считается от старта лидера
+	finish := athlete.StartTimePlanned.Add(30 * time.Minute)
+	athlete.FinishTime = &finish
+	athlete.Status = models.StatusFinished
+	if official, _ := r.OfficialTime(athlete); official != 30*time.Minute+25*time.Second {
+		t.Errorf("OfficialTime() = %v, want gap included", official)
+	}
+}
+
+func TestReadResults_RoundTrip(t *testing.T) {
+	r := createTestRaceWithAthletes()
+	var buf strings.Builder
+	if err := r.WriteJSON(&buf); err != nil {
+		t.Fatalf("WriteJSON() error = %v", err)
+	}
+
+	results, err := ReadResults(strings.NewReader(buf.String()))
+	if err != nil {
+		t.Fatalf("ReadResults() error = %v", err)
+	}
+	if len(results.Results) != len(r.Athletes) || results.Results[0].Gap != "00:00:00.000" {
+		t.Errorf("ReadResults() = %+v", results.Results)
+	}
+
+	if _, err := ReadResults(strings.NewReader("{")); err == nil {
+		t.Error("ReadResults() expected error for broken JSON")
+	}
+}
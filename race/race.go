@@ -6,60 +6,233 @@ import (
 	"biathlon-prototype/models"
 	"biathlon-prototype/utils"
 	"fmt"
+	"io"
+	"os"
+	"sort"
 	"strconv"
-	"strings"
 	"time"
 )
 
 type Race struct {
 	Config        configs.Config
+	Date          time.Time      // Полночь дня гонки; нулевой год, если дата не задана
+	Location      *time.Location // Часовой пояс гонки: в нем читаются времена событий
+	ReportZone    *time.Location // Часовой пояс времен в журнале и отчетах (по умолчанию Location)
 	StartTime     time.Time
 	StartDelta    time.Duration
+	PenaltySpeed  float64       // Скорость, быстрее которой штрафной круг не пройти, м/с
+	LoopPenalty   time.Duration // Штрафное время за каждый непройденный штрафной круг, 0 - только отмечать
+	Legs          int           // Этапов в эстафете (Config.Legs или configs.DefaultRelayLegs)
 	Athletes      map[int]*models.Athlete
-	EventLog      []string
+	Roster        map[int]models.Entry    // Заявки по ID участника (см. SetRoster), nil - ростер не загружен
+	Teams         []*models.Team          // Команды эстафеты по ростеру, по номеру команды
+	Exchanges     []models.Exchange       // Передачи эстафеты по порядку
+	teams         map[string]*models.Team // Команды эстафеты по названию
+	EventLog      []events.Outgoing
+	Violations    []Violation // События, отклоненные из-за нарушения порядка
 	CurrentFiring map[int]int
+	arrivals      map[arrival]int // Сколько участников пришло на каждую по счету стрельбу (для позиций при общем старте)
+	Output        io.Writer       // Куда печатается журнал событий по ходу обработки
+	Renderer      events.Renderer // Как печатаются события в Output
+	Format        Format          // Правила выбранного формата гонки
+	clock         *utils.DayClock // Привязка времени событий к дням с учетом перехода через полночь
 }
 
 func NewRace(cfg configs.Config) (*Race, error) {
+	location := time.UTC
+	if cfg.TimeZone != "" {
+		// Смещение пояса зависит от даты (летнее время), поэтому без даты пояс бессмыслен
+		if cfg.Date == "" {
+			return nil, fmt.Errorf("для часового пояса %q нужна дата гонки", cfg.TimeZone)
+		}
+		loc, err := time.LoadLocation(cfg.TimeZone)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка загрузки часового пояса: %v", err)
+		}
+		location = loc
+	}
+
+	// Без даты времена событий остаются в нулевом году, как их разбирает utils.ParseTime
+	date := time.Date(0, 1, 1, 0, 0, 0, 0, location)
+	if cfg.Date != "" {
+		d, err := time.ParseInLocation(utils.DateLayout, cfg.Date, location)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка парсинга даты гонки: %v", err)
+		}
+		date = d
+	}
+
 	startTime, err := time.Parse("15:04:05", cfg.Start)
 	if err != nil {
 		return nil, fmt.Errorf("ошибка парсинга времени старта: %v", err)
 	}
+	startTime = utils.OnDay(startTime, date)
+
+	startDelta, err := utils.ParseDelta(cfg.StartDelta)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка парсинга стартового интервала: %v", err)
+	}
 
-	startDeltaStr := cfg.StartDelta
-	if strings.Contains(startDeltaStr, ":") {
-		parts := strings.Split(startDeltaStr, ":")
-		if len(parts) == 3 {
-			h, _ := strconv.Atoi(parts[0])
-			m, _ := strconv.Atoi(parts[1])
-			s, _ := strconv.Atoi(parts[2])
-			startDeltaStr = fmt.Sprintf("%dh%dm%ds", h, m, s)
+	penaltySpeed := cfg.PenaltySpeed
+	if penaltySpeed == 0 {
+		penaltySpeed = configs.DefaultPenaltySpeed
+	}
+	loopPenalty := configs.DefaultSkippedLoopPenalty
+	if cfg.SkippedLoopPenalty != "" {
+		loopPenalty, err = utils.ParseDelta(cfg.SkippedLoopPenalty)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка парсинга штрафа за пропущенный круг: %v", err)
 		}
 	}
 
-	startDelta, err := time.ParseDuration(startDeltaStr)
+	legs := cfg.Legs
+	if legs == 0 {
+		legs = configs.DefaultRelayLegs
+	}
+
+	format, err := FormatByName(cfg.Format)
 	if err != nil {
-		return nil, fmt.Errorf("ошибка парсинга стартового интервала: %v", err)
+		return nil, err
 	}
 
 	return &Race{
 		Config:        cfg,
+		Date:          date,
+		Location:      location,
+		ReportZone:    location,
 		StartTime:     startTime,
 		StartDelta:    startDelta,
+		PenaltySpeed:  penaltySpeed,
+		LoopPenalty:   loopPenalty,
+		Legs:          legs,
 		Athletes:      make(map[int]*models.Athlete),
-		EventLog:      make([]string, 0),
+		EventLog:      make([]events.Outgoing, 0),
 		CurrentFiring: make(map[int]int),
+		arrivals:      make(map[arrival]int),
+		Output:        os.Stdout,
+		Renderer:      events.TextRenderer{},
+		Format:        format,
+		clock:         utils.NewDayClock(date),
 	}, nil
 }
 
-func (r *Race) logEvent(format string, args ...interface{}) {
-	msg := fmt.Sprintf(format, args...)
-	r.EventLog = append(r.EventLog, msg)
-	fmt.Println(msg)
+// arrival - стрельба, на которую приходят участники: этап эстафеты
+// (0 в остальных форматах) и порядковый номер стрельбы на нем
+type arrival struct {
+	leg   int
+	stage int
+}
+
+// SetRoster загружает ростер: заявки связываются с участниками по
+// Entry.AthleteID, в том числе с уже известными гонке. В эстафете по
+// ростеру собираются команды (см. Teams); ошибка означает, что состав
+// команд не соответствует Legs, ростер при этом все равно загружен
+func (r *Race) SetRoster(entries []models.Entry) error {
+	r.Roster = make(map[int]models.Entry, len(entries))
+	for _, entry := range entries {
+		r.Roster[entry.AthleteID()] = entry
+	}
+	for _, athlete := range r.Athletes {
+		athlete.Entry = nil
+		if entry, ok := r.Roster[athlete.ID]; ok {
+			athlete.Entry = &entry
+		}
+	}
+	if r.Format.Name() != FormatRelay {
+		return nil
+	}
+	return r.buildTeams(entries)
+}
+
+// joinRoster связывает нового участника с заявкой. Если ростер загружен,
+// а участника в нем нет, это отмечается событием KindNotInRoster
+func (r *Race) joinRoster(athlete *models.Athlete, t time.Time) {
+	if r.Roster == nil {
+		return
+	}
+	entry, ok := r.Roster[athlete.ID]
+	if !ok {
+		r.logEvent(t, events.KindNotInRoster, athlete.ID, nil)
+		return
+	}
+	athlete.Entry = &entry
+}
+
+// UnlistedAthletes возвращает по возрастанию ID участников, которых нет
+// в загруженном ростере. Без ростера возвращает nil
+func (r *Race) UnlistedAthletes() []int {
+	if r.Roster == nil {
+		return nil
+	}
+	var ids []int
+	for id, athlete := range r.Athletes {
+		if athlete.Entry == nil {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// logEvent добавляет исходящее событие в журнал и печатает его в Output
+func (r *Race) logEvent(t time.Time, kind events.Kind, athleteID int, payload events.Payload) {
+	event := events.Outgoing{
+		Time:      t,
+		Kind:      kind,
+		AthleteID: athleteID,
+		Payload:   payload,
+	}
+	r.EventLog = append(r.EventLog, event)
+	fmt.Fprintln(r.Output, r.Renderer.Render(r.ReportEvent(event)))
+}
+
+// ReportTime переводит время в часовой пояс отчетов
+func (r *Race) ReportTime(t time.Time) time.Time {
+	return t.In(r.ReportZone)
+}
+
+// ReportEvent возвращает копию исходящего события со временем (и временем
+// старта в нагрузке) в часовом поясе отчетов, чтобы напечатать его
+func (r *Race) ReportEvent(event events.Outgoing) events.Outgoing {
+	event.Time = r.ReportTime(event.Time)
+	if p, ok := event.Payload.(events.StartScheduled); ok {
+		event.Payload = events.StartScheduled{Start: r.ReportTime(p.Start)}
+	}
+	return event
 }
 
-func (r *Race) HandleEvent(event events.Event) {
+// HandleEvent применяет событие к гонке. Время события читается в часовом
+// поясе гонки, а без даты привязывается ко дню гонки с учетом перехода
+// через полночь. Если событие неизвестно, у него некорректные параметры
+// или оно недопустимо в текущем состоянии участника, гонка не меняется,
+// а возвращается Violation с причиной (она же добавляется в Race.Violations)
+func (r *Race) HandleEvent(event events.Event) error {
+	// Привязываем время к дню гонки: после полуночи начинаются следующие сутки.
+	// Часы сдвигаются, только если событие принято
+	event.Time = r.clock.Peek(event.Time)
+
 	athlete, exists := r.Athletes[event.AthleteID]
+
+	if kind, err := r.CheckParams(event); err != nil {
+		return r.reject(athlete, event, kind, err)
+	}
+
+	// Проверяем, допустимо ли событие в текущем состоянии участника
+	nextState, err := r.nextState(athlete, event)
+	if err != nil {
+		return r.reject(athlete, event, ViolationOrder, err)
+	}
+	if err := r.checkShot(athlete, event); err != nil {
+		return r.reject(athlete, event, ViolationBadParams, err)
+	}
+	if err := r.checkStartMode(event); err != nil {
+		return r.reject(athlete, event, ViolationOrder, err)
+	}
+	if err := r.checkRelay(athlete, event); err != nil {
+		return r.reject(athlete, event, ViolationOrder, err)
+	}
+	r.clock.Commit(event.Time)
+
 	if !exists {
 		athlete = &models.Athlete{
 			ID:              event.AthleteID,
@@ -69,94 +242,129 @@ func (r *Race) HandleEvent(event events.Event) {
 			PenaltyTimes:    make([]time.Duration, 0),
 		}
 		r.Athletes[event.AthleteID] = athlete
+		r.joinRoster(athlete, event.Time)
 	}
 
 	switch event.EventID {
 	case events.EventRegister:
 		athlete.RegisteredAt = event.Time
 		athlete.Status = models.StatusNotStarted
-		r.logEvent("[%s] Участник(%d) зарегистрирован",
-			utils.FormatTime(event.Time), athlete.ID)
+		r.logEvent(event.Time, events.KindRegistered, athlete.ID, nil)
 
 	case events.EventStartTimeLottery:
 		if len(event.Params) > 0 {
-			startTime, err := time.Parse("15:04:05.000", event.Params[0])
+			startTime, err := utils.ParseTime(event.Params[0])
 			if err == nil {
-				athlete.StartTimePlanned = startTime
-				r.logEvent("[%s] Время старта для участника(%d) установлено жеребьевкой на %s",
-					utils.FormatTime(event.Time), athlete.ID, event.Params[0])
+				// Время старта - в день жеребьевки; на следующий день, только если оно
+				// раньше жеребьевки больше чем на utils.RolloverThreshold (12 ч).
+				// Время чуть раньше жеребьевки остается в тот же день, в прошлом
+				athlete.StartTimePlanned = utils.After(startTime, event.Time)
+				r.logEvent(event.Time, events.KindStartScheduled, athlete.ID,
+					events.StartScheduled{Start: athlete.StartTimePlanned})
 			}
 		}
 
 	case events.EventAtStartLine:
 		athlete.Status = models.StatusRacing
-		r.logEvent("[%s] Участник(%d) на стартовой линии",
-			utils.FormatTime(event.Time), athlete.ID)
+		r.logEvent(event.Time, events.KindAtStartLine, athlete.ID, nil)
 
 	case events.EventStart:
 		now := event.Time
 		athlete.StartTimeActual = &now
 		athlete.Status = models.StatusRacing
-		r.logEvent("[%s] Участник(%d) начал гонку",
-			utils.FormatTime(event.Time), athlete.ID)
+		r.logEvent(event.Time, events.KindStarted, athlete.ID, nil)
 
 	case events.EventAtFiringLine:
 		if len(event.Params) > 0 {
 			firingLine, err := strconv.Atoi(event.Params[0])
 			if err == nil {
+				r.checkPenaltyLoops(athlete, event.Time)
+
 				athlete.FiringLineTimes[firingLine] = event.Time
 				r.CurrentFiring[athlete.ID] = firingLine
-				r.logEvent("[%s] Участник(%d) на огневом рубеже(%d)",
-					utils.FormatTime(event.Time), athlete.ID, firingLine)
+
+				stage := models.ShootingStage{
+					Stage:    len(athlete.Stages) + 1,
+					Line:     firingLine,
+					Position: r.Config.StagePosition(len(athlete.Stages) + 1),
+					Arrived:  event.Time,
+					Targets:  make(map[int]bool),
+				}
+				r.arrivals[arrival{relayLeg(athlete), stage.Stage}]++
+				if len(event.Params) > 1 {
+					stage.Lane, _ = strconv.Atoi(event.Params[1])
+				} else if r.Format.StartMode() == StartCommon {
+					stage.Lane = r.assignedLane(athlete, stage.Stage)
+				}
+				athlete.Stages = append(athlete.Stages, stage)
+				r.logEvent(event.Time, events.KindAtFiringLine, athlete.ID,
+					events.AtFiringLine{Line: firingLine, Lane: stage.Lane})
 			}
 		}
 
 	case events.EventHitSuccessful:
 		if len(event.Params) > 0 {
+			target, _ := strconv.Atoi(event.Params[0])
 			athlete.Hits++
 			athlete.Shots++
-			r.logEvent("[%s] Участник(%d) попал в мишень %s",
-				utils.FormatTime(event.Time), athlete.ID, event.Params[0])
+			recordShot(athlete, target, true)
+			r.logEvent(event.Time, events.KindHit, athlete.ID, events.Shot{Target: target})
 		}
 
 	case events.EventHitMissed:
 		if len(event.Params) > 0 {
+			target, _ := strconv.Atoi(event.Params[0])
 			athlete.Shots++ // Только счетчик выстрелов
-			r.logEvent("[%s] Участник(%d) промахнулся по мишени %s",
-				utils.FormatTime(event.Time), athlete.ID, event.Params[0])
-			// Автоматический штрафной круг за каждый промах
-			athlete.PenaltyTimes = append(athlete.PenaltyTimes, 0)
+			recordShot(athlete, target, false)
+			r.logEvent(event.Time, events.KindMiss, athlete.ID, events.Shot{Target: target})
+			// Штраф за промах зависит от формата гонки
+			r.Format.Penalize(athlete)
 		}
 
+	case events.EventSpareRound:
+		stage := athlete.CurrentStage()
+		stage.Spares++
+		r.logEvent(event.Time, events.KindSpareLoaded, athlete.ID,
+			events.SpareLoaded{Stage: stage.Stage, Spares: stage.Spares})
+
 	case events.EventLeaveFiringLine:
+		if stage := athlete.CurrentStage(); stage != nil {
+			stage.Left = event.Time
+		}
+		r.Format.SettleStage(athlete)
 		firingLine := r.CurrentFiring[athlete.ID]
 		if startTime, exists := athlete.FiringLineTimes[firingLine]; exists {
 			timeSpent := event.Time.Sub(startTime)
-			r.logEvent("[%s] Участник(%d) покинул огневой рубеж(%d) (время: %v)",
-				utils.FormatTime(event.Time), athlete.ID, firingLine, timeSpent)
+			r.logEvent(event.Time, events.KindLeftFiringLine, athlete.ID,
+				events.LeftFiringLine{Line: firingLine, RangeTime: timeSpent})
 		}
 
 	case events.EventEnterPenalty:
-		athlete.PenaltyTimes = append(athlete.PenaltyTimes, 0)
-		r.logEvent("[%s] Участник(%d) вошел на штрафные круги",
-			utils.FormatTime(event.Time), athlete.ID)
+		// Штрафной круг относится к последней стрельбе участника
+		loop := models.PenaltyLoop{Entered: event.Time}
+		if stage := athlete.CurrentStage(); stage != nil {
+			loop.Stage = stage.Stage
+			loop.Line = stage.Line
+		}
+		athlete.PenaltyLoops = append(athlete.PenaltyLoops, loop)
+		r.logEvent(event.Time, events.KindEnteredPenalty, athlete.ID, nil)
 
 	case events.EventLeavePenalty:
-		if len(athlete.PenaltyTimes) > 0 {
-			penaltyIdx := len(athlete.PenaltyTimes) - 1
-			if firingLine, ok := r.CurrentFiring[athlete.ID]; ok {
-				if startTime, exists := athlete.FiringLineTimes[firingLine]; exists {
-					penaltyTime := event.Time.Sub(startTime)
-					athlete.PenaltyTimes[penaltyIdx] = penaltyTime
-					// Расчет общего штрафа
-					athlete.TotalPenalty += int(penaltyTime.Seconds())
-					r.logEvent("[%s] Участник(%d) покинул штрафные круги (время: %v, общий штраф: %d сек)",
-						utils.FormatTime(event.Time), athlete.ID, penaltyTime, athlete.TotalPenalty)
-				}
-			}
+		// Состояние Penalty гарантирует, что вход на круг записан
+		loop := &athlete.PenaltyLoops[len(athlete.PenaltyLoops)-1]
+		loop.Left = event.Time
+		penaltyTime := loop.Duration()
+		athlete.PenaltyTimes = append(athlete.PenaltyTimes, penaltyTime)
+		if loop.Stage > 0 {
+			athlete.Stages[loop.Stage-1].LoopsServed++
 		}
+		// Расчет общего штрафа
+		athlete.TotalPenalty += int(penaltyTime.Seconds())
+		r.logEvent(event.Time, events.KindLeftPenalty, athlete.ID,
+			events.LeftPenalty{LoopTime: penaltyTime, TotalSeconds: athlete.TotalPenalty})
 
 	case events.EventLapFinish:
+		r.checkPenaltyLoops(athlete, event.Time)
 		athlete.CurrentLap++
 		if athlete.CurrentLap <= r.Config.Laps && athlete.StartTimeActual != nil {
 			var lapTime time.Duration
@@ -169,9 +377,11 @@ func (r *Race) HandleEvent(event events.Event) {
 			}
 			athlete.LapTimes = append(athlete.LapTimes, lapTime)
 			athlete.LastLapTime = event.Time // Добавляем новое поле для хранения времени последнего круга
-			r.logEvent("[%s] Участник(%d) завершил круг %d (время круга: %v, общее время: %v)",
-				utils.FormatTime(event.Time), athlete.ID, athlete.CurrentLap,
-				lapTime, event.Time.Sub(*athlete.StartTimeActual))
+			r.logEvent(event.Time, events.KindLapFinished, athlete.ID, events.LapFinished{
+				Lap:     athlete.CurrentLap,
+				LapTime: lapTime,
+				Total:   event.Time.Sub(*athlete.StartTimeActual),
+			})
 		}
 
 	case events.EventCantContinue:
@@ -180,36 +390,178 @@ func (r *Race) HandleEvent(event events.Event) {
 		if len(event.Params) > 0 {
 			reason = event.Params[0]
 		}
-		r.logEvent("[%s] Участник(%d) не может продолжить: %s",
-			utils.FormatTime(event.Time), athlete.ID, reason)
+		r.logEvent(event.Time, events.KindCantContinue, athlete.ID, events.Reason{Text: reason})
+
+	case events.EventExchange:
+		r.checkPenaltyLoops(athlete, event.Time)
+		r.exchange(athlete, r.Athletes[exchangeTarget(event)], event.Time)
 
 	case events.EventDisqualified:
 		athlete.Status = models.StatusDisqualified
-		r.logEvent("[%s] Участник(%d) дисквалифицирован",
-			utils.FormatTime(event.Time), athlete.ID)
+		r.logEvent(event.Time, events.KindDisqualified, athlete.ID, nil)
 
 	case events.EventFinished:
+		r.checkPenaltyLoops(athlete, event.Time)
 		now := event.Time
 		athlete.FinishTime = &now
+		if len(event.Params) > 0 {
+			athlete.PhotoFinish, _ = strconv.Atoi(event.Params[0])
+		}
 		athlete.Status = models.StatusFinished
-		r.logEvent("[%s] Участник(%d) финишировал",
-			utils.FormatTime(event.Time), athlete.ID)
+		r.logEvent(event.Time, events.KindFinished, athlete.ID, nil)
+	}
+
+	athlete.State = nextState
+
+	// Если формат сам назначает время старта (общий старт), жеребьевка не нужна
+	plannedStart := r.Format.PlannedStart(r, athlete)
+	if athlete.State == models.StateRegistered && !plannedStart.IsZero() {
+		athlete.State = models.StateScheduled
 	}
 
 	// Автоматическая дисквалификация за опоздание на старт
-	if athlete.Status == models.StatusNotStarted &&
-		event.Time.After(athlete.StartTimePlanned.Add(r.StartDelta)) {
-		athlete.Status = models.StatusDisqualified
-		r.logEvent("[%s] Участник(%d) дисквалифицирован (не стартовал вовремя)",
-			utils.FormatTime(event.Time), athlete.ID)
+	if r.Format.StartMode() == StartCommon {
+		r.checkCommonStart(event.Time)
+	} else if athlete.Status == models.StatusNotStarted && !plannedStart.IsZero() &&
+		event.Time.After(plannedStart.Add(r.StartDelta)) {
+		r.disqualifyNotStarted(athlete, event.Time)
+	}
+	return nil
+}
+
+// checkCommonStart дисквалифицирует всех, кто не стартовал в течение
+// StartDelta после общего старта. Проверка идет по любому событию гонки,
+// поэтому не зависит от событий самого опоздавшего участника
+func (r *Race) checkCommonStart(t time.Time) {
+	if !t.After(r.StartTime.Add(r.StartDelta)) {
+		return
+	}
+	ids := make([]int, 0, len(r.Athletes))
+	for id := range r.Athletes {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	for _, id := range ids {
+		athlete := r.Athletes[id]
+		// Выход на стартовую линию (3) уже переводит участника в Racing,
+		// поэтому опоздание определяется по фактическому старту. Этапы
+		// эстафеты после первого стартуют с передачи, а не с общего старта
+		if athlete.StartTimeActual == nil && beforeStart(athlete.State) &&
+			!r.Format.PlannedStart(r, athlete).IsZero() {
+			r.disqualifyNotStarted(athlete, t)
+		}
+	}
+}
+
+// disqualifyNotStarted дисквалифицирует участника, не стартовавшего вовремя
+func (r *Race) disqualifyNotStarted(athlete *models.Athlete, t time.Time) {
+	athlete.Status = models.StatusDisqualified
+	athlete.State = models.StateOut
+	r.logEvent(t, events.KindDisqualified, athlete.ID, events.Reason{Text: events.ReasonNotStarted})
+}
+
+// assignedLane возвращает стрелковую позицию при общем старте, если она не
+// указана в событии 5: на первой стрельбе - по нагрудному номеру (или ID,
+// если ростера нет), на следующих - по порядку прихода на рубеж. В эстафете
+// на первой стрельбе первого этапа позиция - по номеру команды, дальше -
+// по порядку прихода на каждую стрельбу каждого этапа
+func (r *Race) assignedLane(athlete *models.Athlete, stage int) int {
+	leg := relayLeg(athlete)
+	if stage > 1 || leg > 1 {
+		return r.arrivals[arrival{leg, stage}]
+	}
+	if team := r.teamOf(athlete); team != nil {
+		return team.ID
+	}
+	if athlete.Entry != nil && athlete.Entry.Bib > 0 {
+		return athlete.Entry.Bib
+	}
+	return athlete.ID
+}
+
+// recordShot записывает выстрел в текущую стрельбу участника.
+// Состояние AtFiringLine гарантирует, что стрельба уже начата
+func recordShot(athlete *models.Athlete, target int, hit bool) {
+	stage := athlete.CurrentStage()
+	if stage == nil {
+		return
+	}
+	stage.Shots++
+	if hit {
+		stage.Hits++
+	}
+	// Промах по уже закрытой мишени не открывает ее
+	if !stage.Targets[target] {
+		stage.Targets[target] = hit
+	}
+}
+
+// checkPenaltyLoops сверяет штрафные круги после последней стрельбы участника,
+// когда он ушел дальше по дистанции (на круг, следующий рубеж или финиш).
+// Если пройдено меньше кругов, чем промахов, участник отмечается событием
+// KindPenaltySkipped. Если кругов достаточно, но на них ушло меньше времени,
+// чем нужно на положенную дистанцию со скоростью PenaltySpeed, - событием
+// KindPenaltySuspect. За каждый непройденный круг начисляется LoopPenalty.
+// Каждая стрельба сверяется один раз
+func (r *Race) checkPenaltyLoops(athlete *models.Athlete, t time.Time) {
+	stage := athlete.CurrentStage()
+	if stage == nil || stage.PenaltyChecked {
+		return
+	}
+	stage.PenaltyChecked = true
+	if stage.LoopsOwed == 0 {
+		return
+	}
+
+	if stage.LoopsServed < stage.LoopsOwed {
+		r.logEvent(t, events.KindPenaltySkipped, athlete.ID, events.PenaltySkipped{
+			Stage:  stage.Stage,
+			Owed:   stage.LoopsOwed,
+			Served: stage.LoopsServed,
+		})
+		athlete.LoopPenalty += time.Duration(stage.LoopsOwed-stage.LoopsServed) * r.LoopPenalty
+		return
+	}
+
+	var served time.Duration
+	for _, loop := range athlete.PenaltyLoops {
+		if loop.Stage == stage.Stage {
+			served += loop.Duration()
+		}
+	}
+	loopLen := float64(r.Config.PenaltyLength(stage.Line))
+	minimum := time.Duration(float64(stage.LoopsOwed) * loopLen / r.PenaltySpeed * float64(time.Second))
+	if served >= minimum {
+		return
+	}
+
+	// За время на кругах с предельной скоростью можно пройти только столько кругов
+	plausible := int(served.Seconds() * r.PenaltySpeed / loopLen)
+	stage.LoopsSuspected = stage.LoopsOwed - plausible
+	athlete.LoopPenalty += time.Duration(stage.LoopsSuspected) * r.LoopPenalty
+	r.logEvent(t, events.KindPenaltySuspect, athlete.ID, events.PenaltySuspect{
+		Stage:   stage.Stage,
+		Owed:    stage.LoopsOwed,
+		Served:  served,
+		Minimum: minimum,
+	})
+}
+
+// penaltyLength возвращает длину i-го (с 0) штрафного круга участника
+// по огневому рубежу стрельбы, после которой он пройден
+func (r *Race) penaltyLength(a *models.Athlete, i int) int {
+	line := 0 // Рубеж неизвестен - общая длина штрафного круга
+	if i < len(a.PenaltyLoops) {
+		line = a.PenaltyLoops[i].Line
 	}
+	return r.Config.PenaltyLength(line)
 }
 
 // CalculateStats вычисляет дополнительную статистику по участникам
 func (r *Race) CalculateStats() {
 	for _, a := range r.Athletes {
-		// Общая дистанция (только завершенные круги)
-		a.TotalDistance = r.Config.LapLen * len(a.LapTimes)
+		// Общая дистанция (только завершенные круги, по профилю трассы)
+		a.TotalDistance = r.Config.DistanceLength(len(a.LapTimes))
 
 		// Средняя скорость (если гонка завершена)
 		if a.StartTimeActual != nil && a.FinishTime != nil {
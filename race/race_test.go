@@ -4,6 +4,8 @@ import (
 	"biathlon-prototype/configs"
 	"biathlon-prototype/events"
 	"biathlon-prototype/models"
+	"biathlon-prototype/utils"
+	"io"
 	"testing"
 	"time"
 )
@@ -59,6 +61,8 @@ func TestHandleEvent_Registration(t *testing.T) {
 func TestHandleEvent_Start(t *testing.T) {
 	r := createTestRace()
 	registerAthlete(r, 1)
+	r.HandleEvent(createTestEvent(events.EventStartTimeLottery, "09:05:00.000", 1, "10:00:00.000"))
+	r.HandleEvent(createTestEvent(events.EventAtStartLine, "09:59:00.000", 1))
 	event := createTestEvent(events.EventStart, "10:00:00.000", 1)
 
 	r.HandleEvent(event)
@@ -115,8 +119,10 @@ func TestHandleEvent_Shooting(t *testing.T) {
 		t.Errorf("Expected 2 shots, got %d", athlete.Shots)
 	}
 
-	if len(athlete.PenaltyTimes) != 1 {
-		t.Errorf("Expected 1 penalty, got %d", len(athlete.PenaltyTimes))
+	// Промах добавляет долг по штрафным кругам, а не пустой штрафной круг
+	if athlete.LoopsOwed() != 1 || len(athlete.PenaltyTimes) != 0 {
+		t.Errorf("Expected 1 loop owed and none served, got %d owed, %d served",
+			athlete.LoopsOwed(), len(athlete.PenaltyTimes))
 	}
 }
 
@@ -197,16 +203,549 @@ func registerAndStartAthlete(r *Race, id int) {
 	registerAthlete(r, id)
 	lotteryEvent := createTestEvent(events.EventStartTimeLottery, "09:05:00.000", id, "10:00:00.000")
 	r.HandleEvent(lotteryEvent)
+	startLineEvent := createTestEvent(events.EventAtStartLine, "09:59:00.000", id)
+	r.HandleEvent(startLineEvent)
 	startEvent := createTestEvent(events.EventStart, "10:00:00.000", id)
 	r.HandleEvent(startEvent)
 }
 
 func finishAthlete(r *Race, id int) {
-	// Simulate full race
-	lap1 := createTestEvent(events.EventLapFinish, "10:30:00.000", id)
-	r.HandleEvent(lap1)
-	lap2 := createTestEvent(events.EventLapFinish, "11:00:00.000", id)
-	r.HandleEvent(lap2)
-	finish := createTestEvent(events.EventFinished, "11:30:00.000", id)
+	// Simulate full race: every lap takes 30 minutes
+	lapEnd := time.Date(0, 1, 1, 10, 0, 0, 0, time.UTC)
+	for lap := 0; lap < r.Config.Laps; lap++ {
+		lapEnd = lapEnd.Add(30 * time.Minute)
+		r.HandleEvent(createTestEvent(events.EventLapFinish, lapEnd.Format("15:04:05.000"), id))
+	}
+	finish := createTestEvent(events.EventFinished, lapEnd.Add(time.Second).Format("15:04:05.000"), id)
 	r.HandleEvent(finish)
 }
+
+func TestHandleEvent_OutgoingEvents(t *testing.T) {
+	r := createTestRace()
+	registerAndStartAthlete(r, 1)
+	finishAthlete(r, 1)
+
+	last := r.EventLog[len(r.EventLog)-1]
+	if last.Kind != events.KindFinished || last.AthleteID != 1 {
+		t.Errorf("Expected last event to be finish of athlete 1, got %+v", last)
+	}
+
+	// Время старта участника 2 прошло раньше, чем пришло следующее его событие
+	registerAthlete(r, 2)
+	r.HandleEvent(createTestEvent(events.EventStartTimeLottery, "10:05:00.000", 2, "10:01:00.000"))
+
+	last = r.EventLog[len(r.EventLog)-1]
+	if last.Kind != events.KindDisqualified || last.Payload != (events.Reason{Text: events.ReasonNotStarted}) {
+		t.Errorf("Expected not-started disqualification, got %+v", last)
+	}
+}
+
+func TestHandleEvent_RejectedEventKeepsDay(t *testing.T) {
+	cfg := configs.Config{
+		Laps:        1,
+		LapLen:      4000,
+		PenaltyLen:  150,
+		FiringLines: 1,
+		Start:       "09:30:00",
+		StartDelta:  "00:01:00",
+		Date:        "2026-03-01",
+	}
+	r, err := NewRace(cfg)
+	if err != nil {
+		t.Fatalf("NewRace() error = %v", err)
+	}
+	r.Output = io.Discard
+
+	for _, e := range []events.Event{
+		createTestEvent(events.EventRegister, "09:00:00.000", 1),
+		createTestEvent(events.EventStartTimeLottery, "09:05:00.000", 1, "09:30:00.000"),
+		createTestEvent(99, "23:59:00.000", 1),                   // Опечатка: неизвестное событие
+		createTestEvent(events.EventFinished, "23:58:00.000", 1), // Недопустимо до старта
+		createTestEvent(events.EventAtStartLine, "09:29:00.000", 1),
+		createTestEvent(events.EventStart, "09:30:00.000", 1),
+		createTestEvent(events.EventLapFinish, "09:50:00.000", 1),
+		createTestEvent(events.EventFinished, "09:50:01.000", 1),
+	} {
+		r.HandleEvent(e)
+	}
+	if len(r.Violations) != 2 {
+		t.Fatalf("Violations = %v, want 2 rejected events", r.Violations)
+	}
+
+	athlete := r.Athletes[1]
+	wantFinish := time.Date(2026, 3, 1, 9, 50, 1, 0, time.UTC)
+	if athlete.FinishTime == nil || !athlete.FinishTime.Equal(wantFinish) {
+		t.Errorf("Finish = %v, want %v: rejected events must not move the day", athlete.FinishTime, wantFinish)
+	}
+	if official, _ := r.OfficialTime(athlete); official != 20*time.Minute+time.Second {
+		t.Errorf("Official time = %v, want 20m1s", official)
+	}
+}
+
+func TestHandleEvent_MidnightRollover(t *testing.T) {
+	cfg := configs.Config{
+		Laps:        2,
+		LapLen:      4000,
+		PenaltyLen:  150,
+		FiringLines: 1,
+		Start:       "23:30:00",
+		StartDelta:  "00:01:00",
+		Date:        "2025-12-31",
+	}
+	r, err := NewRace(cfg)
+	if err != nil {
+		t.Fatalf("NewRace() error = %v", err)
+	}
+	r.Output = io.Discard
+
+	for _, e := range []events.Event{
+		createTestEvent(events.EventRegister, "23:00:00.000", 1),
+		createTestEvent(events.EventStartTimeLottery, "23:05:00.000", 1, "23:50:00.000"),
+		createTestEvent(events.EventAtStartLine, "23:49:00.000", 1),
+		createTestEvent(events.EventStart, "23:50:00.000", 1),
+		createTestEvent(events.EventLapFinish, "00:10:00.000", 1), // После полуночи
+		createTestEvent(events.EventLapFinish, "00:30:00.000", 1),
+		createTestEvent(events.EventFinished, "00:30:01.000", 1),
+	} {
+		if err := r.HandleEvent(e); err != nil {
+			t.Fatalf("HandleEvent() unexpected error = %v", err)
+		}
+	}
+
+	athlete := r.Athletes[1]
+	if athlete.LapTimes[0] != 20*time.Minute {
+		t.Errorf("Expected first lap 20m across midnight, got %v", athlete.LapTimes[0])
+	}
+
+	wantFinish := time.Date(2026, 1, 1, 0, 30, 1, 0, time.UTC)
+	if !athlete.FinishTime.Equal(wantFinish) {
+		t.Errorf("Expected finish at %v, got %v", wantFinish, athlete.FinishTime)
+	}
+
+	official, _ := r.OfficialTime(athlete)
+	if official != 40*time.Minute+time.Second {
+		t.Errorf("Expected official time 40m1s, got %v", official)
+	}
+}
+
+func TestHandleEvent_DatedEvents(t *testing.T) {
+	r := createTestRace()
+	r.Output = io.Discard
+
+	// Многодневная серия: время события с датой используется как есть
+	event, err := events.ParseEvent("[2026-02-01T09:00:00.000] 1 1")
+	if err != nil {
+		t.Fatalf("ParseEvent() error = %v", err)
+	}
+	r.HandleEvent(event)
+	r.HandleEvent(createTestEvent(events.EventStartTimeLottery, "09:05:00.000", 1, "10:00:00.000"))
+
+	athlete := r.Athletes[1]
+	want := time.Date(2026, 2, 1, 10, 0, 0, 0, time.UTC)
+	if !athlete.StartTimePlanned.Equal(want) {
+		t.Errorf("Expected planned start %v, got %v", want, athlete.StartTimePlanned)
+	}
+}
+
+func createZonedRace(t *testing.T, date, start string) *Race {
+	t.Helper()
+	r, err := NewRace(configs.Config{
+		Laps:        1,
+		LapLen:      4000,
+		PenaltyLen:  150,
+		FiringLines: 1,
+		Start:       start,
+		StartDelta:  "00:01:00",
+		Date:        date,
+		TimeZone:    "Europe/Oslo",
+	})
+	if err != nil {
+		t.Fatalf("NewRace() error = %v", err)
+	}
+	r.Output = io.Discard
+	return r
+}
+
+func TestNewRace_TimeZone(t *testing.T) {
+	r := createZonedRace(t, "2026-03-01", "10:00:00")
+
+	// В марте Осло живет по UTC+1
+	want := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	if !r.StartTime.Equal(want) {
+		t.Errorf("Expected start %v, got %v", want, r.StartTime.UTC())
+	}
+
+	for _, cfg := range []configs.Config{
+		{Start: "10:00:00", StartDelta: "00:01:00", TimeZone: "Europe/Oslo"},
+		{Start: "10:00:00", StartDelta: "00:01:00", Date: "2026-03-01", TimeZone: "Mars/Olympus"},
+	} {
+		if _, err := NewRace(cfg); err == nil {
+			t.Errorf("NewRace(%+v) expected error", cfg)
+		}
+	}
+}
+
+func TestHandleEvent_TimeZone(t *testing.T) {
+	// В ночь на 29 марта 2026 года часы в Осло переводятся с 02:00 на 03:00
+	r := createZonedRace(t, "2026-03-29", "01:30:00")
+
+	for _, e := range []events.Event{
+		createTestEvent(events.EventRegister, "01:00:00.000", 1),
+		createTestEvent(events.EventStartTimeLottery, "01:05:00.000", 1, "01:30:00.000"),
+		createTestEvent(events.EventAtStartLine, "01:29:00.000", 1),
+		createTestEvent(events.EventStart, "01:30:00.000", 1),
+		createTestEvent(events.EventLapFinish, "03:10:00.000", 1),
+		createTestEvent(events.EventFinished, "03:10:00.000", 1),
+	} {
+		if err := r.HandleEvent(e); err != nil {
+			t.Fatalf("HandleEvent() unexpected error = %v", err)
+		}
+	}
+
+	athlete := r.Athletes[1]
+	if athlete.LapTimes[0] != 40*time.Minute {
+		t.Errorf("Expected lap of 40m across the clock change, got %v", athlete.LapTimes[0])
+	}
+	if got := utils.FormatTime(r.ReportTime(*athlete.FinishTime)); got != "2026-03-29T03:10:00.000" {
+		t.Errorf("Expected local finish 2026-03-29T03:10:00.000, got %s", got)
+	}
+
+	r.ReportZone = time.UTC
+	if got := utils.FormatTime(r.ReportTime(*athlete.FinishTime)); got != "2026-03-29T01:10:00.000" {
+		t.Errorf("Expected UTC finish 2026-03-29T01:10:00.000, got %s", got)
+	}
+	if got := r.Results().TimeZone; got != "UTC" {
+		t.Errorf("Expected results time zone UTC, got %s", got)
+	}
+}
+
+func TestCalculateStats_CourseProfile(t *testing.T) {
+	r := createTestRace()
+	r.Config.LapLens = []int{3000, 3500, 4000}
+	r.Config.PenaltyLens = []int{150, 100}
+	r.Output = io.Discard
+	registerAndStartAthlete(r, 1)
+
+	// Промах на втором рубеже и штрафной круг длиной 100 м
+	for _, e := range []events.Event{
+		createTestEvent(events.EventAtFiringLine, "10:10:00.000", 1, "2"),
+		createTestEvent(events.EventHitMissed, "10:10:10.000", 1, "1"),
+		createTestEvent(events.EventLeaveFiringLine, "10:10:30.000", 1),
+		createTestEvent(events.EventEnterPenalty, "10:10:31.000", 1),
+		createTestEvent(events.EventLeavePenalty, "10:11:20.000", 1),
+	} {
+		r.HandleEvent(e)
+	}
+	finishAthlete(r, 1)
+	r.CalculateStats()
+
+	athlete := r.Athletes[1]
+	if athlete.TotalDistance != 10500 {
+		t.Errorf("Expected total distance 10500 m, got %d", athlete.TotalDistance)
+	}
+
+	result := r.Results().Results[0]
+	// Круги по 30 минут: 3000, 3500 и 4000 м
+	for i, want := range []float64{1.67, 1.94, 2.22} {
+		if result.Laps[i].Speed != want {
+			t.Errorf("Lap %d speed = %.2f, want %.2f", i+1, result.Laps[i].Speed, want)
+		}
+	}
+
+	last := result.Penalties[len(result.Penalties)-1]
+	want := round2(100 / athlete.PenaltyTimes[len(athlete.PenaltyTimes)-1].Seconds())
+	if last.Speed != want {
+		t.Errorf("Penalty speed = %.2f, want %.2f for the 100 m loop of line 2", last.Speed, want)
+	}
+}
+
+func TestHandleEvent_ShootingStages(t *testing.T) {
+	r := createTestRace()
+	r.Config.Stages = []string{"prone", "standing"}
+	r.Output = io.Discard
+	registerAndStartAthlete(r, 1)
+
+	for _, e := range []events.Event{
+		createTestEvent(events.EventAtFiringLine, "10:10:00.000", 1, "1", "12"),
+		createTestEvent(events.EventHitSuccessful, "10:10:05.000", 1, "1"),
+		createTestEvent(events.EventHitMissed, "10:10:08.000", 1, "2"),
+		createTestEvent(events.EventHitSuccessful, "10:10:11.000", 1, "3"),
+		createTestEvent(events.EventLeaveFiringLine, "10:10:30.000", 1),
+		createTestEvent(events.EventLapFinish, "10:30:00.000", 1),
+		// Тот же рубеж номер 1 на втором круге - новая стрельба, а не перезапись
+		createTestEvent(events.EventAtFiringLine, "10:40:00.000", 1, "1"),
+		createTestEvent(events.EventHitSuccessful, "10:40:05.000", 1, "4"),
+		createTestEvent(events.EventHitSuccessful, "10:40:10.000", 1, "5"),
+	} {
+		if err := r.HandleEvent(e); err != nil {
+			t.Fatalf("HandleEvent() unexpected error = %v", err)
+		}
+	}
+
+	athlete := r.Athletes[1]
+	if len(athlete.Stages) != 2 {
+		t.Fatalf("Expected 2 shooting stages, got %d", len(athlete.Stages))
+	}
+
+	first := athlete.Stages[0]
+	if first.Stage != 1 || first.Line != 1 || first.Lane != 12 || first.Position != "prone" {
+		t.Errorf("First stage = %+v, want stage 1, line 1, lane 12, prone", first)
+	}
+	if first.Shots != 3 || first.Hits != 2 || first.Misses() != 1 {
+		t.Errorf("First stage shots/hits = %d/%d, want 3/2", first.Shots, first.Hits)
+	}
+	if !first.Targets[1] || first.Targets[2] || !first.Targets[3] {
+		t.Errorf("First stage targets = %v, want 1 and 3 closed, 2 open", first.Targets)
+	}
+	if first.RangeTime() != 30*time.Second {
+		t.Errorf("First stage range time = %v, want 30s", first.RangeTime())
+	}
+
+	second := athlete.Stages[1]
+	if second.Position != "standing" || second.Lane != 0 || second.RangeTime() != 0 {
+		t.Errorf("Second stage = %+v, want standing without lane, still on range", second)
+	}
+
+	result := r.Results().Results[0]
+	if result.Prone == nil || result.Prone.Accuracy != 66.67 {
+		t.Errorf("Prone = %+v, want accuracy 66.67", result.Prone)
+	}
+	if result.Standing == nil || result.Standing.Accuracy != 100 {
+		t.Errorf("Standing = %+v, want accuracy 100", result.Standing)
+	}
+	if got := result.Shooting[0].Targets; len(got) != 2 || got[0] != 1 || got[1] != 3 {
+		t.Errorf("Shooting[0].Targets = %v, want [1 3]", got)
+	}
+	if result.Misses != "1+0 = 1" {
+		t.Errorf("Misses = %q, want \"1+0 = 1\"", result.Misses)
+	}
+}
+
+func TestHandleEvent_PenaltyLoops(t *testing.T) {
+	r := createTestRace()
+	r.Output = io.Discard
+	registerAndStartAthlete(r, 1)
+
+	for _, e := range []events.Event{
+		createTestEvent(events.EventAtFiringLine, "10:10:00.000", 1, "1"),
+		createTestEvent(events.EventHitMissed, "10:10:10.000", 1, "1"),
+		createTestEvent(events.EventHitMissed, "10:10:15.000", 1, "2"),
+		createTestEvent(events.EventLeaveFiringLine, "10:10:40.000", 1),
+		createTestEvent(events.EventEnterPenalty, "10:11:00.000", 1),
+		createTestEvent(events.EventLeavePenalty, "10:11:30.000", 1),
+		createTestEvent(events.EventEnterPenalty, "10:11:31.000", 1),
+		createTestEvent(events.EventLeavePenalty, "10:12:03.000", 1),
+		createTestEvent(events.EventLapFinish, "10:30:00.000", 1),
+	} {
+		if err := r.HandleEvent(e); err != nil {
+			t.Fatalf("HandleEvent() unexpected error = %v", err)
+		}
+	}
+
+	athlete := r.Athletes[1]
+	// Время круга - от входа до выхода, без времени стрельбы
+	want := []time.Duration{30 * time.Second, 32 * time.Second}
+	if len(athlete.PenaltyTimes) != 2 || athlete.PenaltyTimes[0] != want[0] || athlete.PenaltyTimes[1] != want[1] {
+		t.Errorf("PenaltyTimes = %v, want %v", athlete.PenaltyTimes, want)
+	}
+	if athlete.TotalPenalty != 62 {
+		t.Errorf("TotalPenalty = %d, want 62", athlete.TotalPenalty)
+	}
+	if loop := athlete.PenaltyLoops[0]; loop.Stage != 1 || loop.Line != 1 {
+		t.Errorf("PenaltyLoops[0] = %+v, want stage 1 on line 1", loop)
+	}
+	if athlete.LoopsOwed() != 2 || athlete.LoopsServed() != 2 || athlete.SkippedLoops() != 0 {
+		t.Errorf("Loops owed/served/skipped = %d/%d/%d, want 2/2/0",
+			athlete.LoopsOwed(), athlete.LoopsServed(), athlete.SkippedLoops())
+	}
+	for _, e := range r.EventLog {
+		if e.Kind == events.KindPenaltySkipped {
+			t.Errorf("Unexpected skipped penalty event %+v", e)
+		}
+	}
+}
+
+func TestHandleEvent_SkippedPenaltyLoops(t *testing.T) {
+	r := createTestRace()
+	r.Output = io.Discard
+	registerAndStartAthlete(r, 1)
+
+	// Три промаха, но только один штрафной круг
+	for _, e := range []events.Event{
+		createTestEvent(events.EventAtFiringLine, "10:10:00.000", 1, "1"),
+		createTestEvent(events.EventHitMissed, "10:10:10.000", 1, "1"),
+		createTestEvent(events.EventHitMissed, "10:10:15.000", 1, "2"),
+		createTestEvent(events.EventHitMissed, "10:10:20.000", 1, "3"),
+		createTestEvent(events.EventLeaveFiringLine, "10:10:40.000", 1),
+		createTestEvent(events.EventEnterPenalty, "10:11:00.000", 1),
+		createTestEvent(events.EventLeavePenalty, "10:11:30.000", 1),
+		createTestEvent(events.EventLapFinish, "10:30:00.000", 1),
+		// Следующий рубеж без промахов не отмечается повторно
+		createTestEvent(events.EventAtFiringLine, "10:40:00.000", 1, "2"),
+		createTestEvent(events.EventHitSuccessful, "10:40:10.000", 1, "1"),
+		createTestEvent(events.EventLeaveFiringLine, "10:40:30.000", 1),
+		createTestEvent(events.EventLapFinish, "11:00:00.000", 1),
+	} {
+		if err := r.HandleEvent(e); err != nil {
+			t.Fatalf("HandleEvent() unexpected error = %v", err)
+		}
+	}
+
+	athlete := r.Athletes[1]
+	if athlete.SkippedLoops() != 2 {
+		t.Errorf("SkippedLoops() = %d, want 2", athlete.SkippedLoops())
+	}
+
+	var skipped []events.Outgoing
+	for _, e := range r.EventLog {
+		if e.Kind == events.KindPenaltySkipped {
+			skipped = append(skipped, e)
+		}
+	}
+	if len(skipped) != 1 {
+		t.Fatalf("Expected 1 skipped penalty event, got %d", len(skipped))
+	}
+	if got, want := skipped[0].Payload, (events.PenaltySkipped{Stage: 1, Owed: 3, Served: 1}); got != want {
+		t.Errorf("Skipped penalty payload = %+v, want %+v", got, want)
+	}
+	if got := utils.FormatTime(skipped[0].Time); got != "10:30:00.000" {
+		t.Errorf("Skipped penalty flagged at %s, want 10:30:00.000", got)
+	}
+
+	standing := r.Standings()[0]
+	if standing.LoopsOwed != 3 || standing.LoopsServed != 1 || standing.SkippedLoops != 2 {
+		t.Errorf("Standing loops = %d/%d/%d, want 3 owed, 1 served, 2 skipped",
+			standing.LoopsOwed, standing.LoopsServed, standing.SkippedLoops)
+	}
+}
+
+func TestStandings_NoSkippedLoopsMidStage(t *testing.T) {
+	r := createTestRace()
+	r.Output = io.Discard
+	registerAndStartAthlete(r, 1)
+
+	steps := []struct {
+		event events.Event
+		what  string
+	}{
+		{createTestEvent(events.EventAtFiringLine, "10:10:00.000", 1, "1"), "на рубеже"},
+		{createTestEvent(events.EventHitMissed, "10:10:10.000", 1, "1"), "после промаха"},
+		{createTestEvent(events.EventHitMissed, "10:10:15.000", 1, "2"), "после второго промаха"},
+		{createTestEvent(events.EventLeaveFiringLine, "10:10:40.000", 1), "после ухода с рубежа"},
+		{createTestEvent(events.EventEnterPenalty, "10:11:00.000", 1), "на штрафном круге"},
+		{createTestEvent(events.EventLeavePenalty, "10:11:30.000", 1), "после первого штрафного круга"},
+	}
+	for _, step := range steps {
+		if err := r.HandleEvent(step.event); err != nil {
+			t.Fatalf("HandleEvent() unexpected error = %v", err)
+		}
+		if got := r.Standings()[0].SkippedLoops; got != 0 {
+			t.Errorf("SkippedLoops %s = %d, want 0", step.what, got)
+		}
+	}
+
+	// Участник ушел на дистанцию после одного круга из двух - теперь круг пропущен
+	if err := r.HandleEvent(createTestEvent(events.EventLapFinish, "10:30:00.000", 1)); err != nil {
+		t.Fatalf("HandleEvent() unexpected error = %v", err)
+	}
+	if got := r.Standings()[0].SkippedLoops; got != 1 {
+		t.Errorf("SkippedLoops after lap = %d, want 1", got)
+	}
+}
+
+func TestHandleEvent_SuspiciouslyFastPenaltyLoops(t *testing.T) {
+	cfg := createTestRace().Config
+	cfg.SkippedLoopPenalty = "00:02:00"
+	r, err := NewRace(cfg)
+	if err != nil {
+		t.Fatalf("NewRace() error = %v", err)
+	}
+	r.Output = io.Discard
+	registerAndStartAthlete(r, 1)
+
+	// Три промаха и три отметки о штрафных кругах, но на 450 м ушло 30 секунд:
+	// со скоростью 8 м/с за это время проходится только один круг
+	for _, e := range []events.Event{
+		createTestEvent(events.EventAtFiringLine, "10:10:00.000", 1, "1"),
+		createTestEvent(events.EventHitMissed, "10:10:10.000", 1, "1"),
+		createTestEvent(events.EventHitMissed, "10:10:15.000", 1, "2"),
+		createTestEvent(events.EventHitMissed, "10:10:20.000", 1, "3"),
+		createTestEvent(events.EventLeaveFiringLine, "10:10:40.000", 1),
+		createTestEvent(events.EventEnterPenalty, "10:11:00.000", 1),
+		createTestEvent(events.EventLeavePenalty, "10:11:20.000", 1),
+		createTestEvent(events.EventEnterPenalty, "10:11:20.000", 1),
+		createTestEvent(events.EventLeavePenalty, "10:11:25.000", 1),
+		createTestEvent(events.EventEnterPenalty, "10:11:25.000", 1),
+		createTestEvent(events.EventLeavePenalty, "10:11:30.000", 1),
+		createTestEvent(events.EventLapFinish, "10:30:00.000", 1),
+	} {
+		if err := r.HandleEvent(e); err != nil {
+			t.Fatalf("HandleEvent() unexpected error = %v", err)
+		}
+	}
+
+	athlete := r.Athletes[1]
+	if athlete.SkippedLoops() != 0 || athlete.SuspectedLoops() != 2 {
+		t.Errorf("Skipped/suspected loops = %d/%d, want 0/2", athlete.SkippedLoops(), athlete.SuspectedLoops())
+	}
+	if athlete.LoopPenalty != 4*time.Minute {
+		t.Errorf("LoopPenalty = %v, want 4m", athlete.LoopPenalty)
+	}
+
+	var suspect []events.Outgoing
+	for _, e := range r.EventLog {
+		if e.Kind == events.KindPenaltySuspect {
+			suspect = append(suspect, e)
+		}
+	}
+	if len(suspect) != 1 {
+		t.Fatalf("Expected 1 suspected penalty event, got %d", len(suspect))
+	}
+	want := events.PenaltySuspect{Stage: 1, Owed: 3, Served: 30 * time.Second, Minimum: 56250 * time.Millisecond}
+	if got := suspect[0].Payload; got != want {
+		t.Errorf("Suspected penalty payload = %+v, want %+v", got, want)
+	}
+
+	// Штраф за пропущенные круги входит в официальное время
+	finish := athlete.StartTimePlanned.Add(50 * time.Minute)
+	athlete.FinishTime = &finish
+	athlete.Status = models.StatusFinished
+	official, ok := r.OfficialTime(athlete)
+	if !ok || official != 54*time.Minute {
+		t.Errorf("OfficialTime() = %v, %v, want 54m", official, ok)
+	}
+}
+
+func TestSetRoster(t *testing.T) {
+	r := createTestRace()
+	r.Output = io.Discard
+	registerAthlete(r, 1)
+
+	// Заявка без ID связывается по нагрудному номеру, в том числе с уже известным участником
+	r.SetRoster([]models.Entry{
+		{Bib: 1, Name: "Johannes Boe", Nation: "NOR"},
+		{ID: 2, Bib: 12, Name: "Sturla Laegreid", Nation: "NOR"},
+	})
+	registerAthlete(r, 2)
+	registerAthlete(r, 3)
+
+	if entry := r.Athletes[1].Entry; entry == nil || entry.Name != "Johannes Boe" {
+		t.Errorf("Athletes[1].Entry = %+v, want Johannes Boe", entry)
+	}
+	if entry := r.Athletes[2].Entry; entry == nil || entry.Bib != 12 {
+		t.Errorf("Athletes[2].Entry = %+v, want bib 12", entry)
+	}
+	if got := r.UnlistedAthletes(); len(got) != 1 || got[0] != 3 {
+		t.Errorf("UnlistedAthletes() = %v, want [3]", got)
+	}
+
+	var unlisted []int
+	for _, e := range r.EventLog {
+		if e.Kind == events.KindNotInRoster {
+			unlisted = append(unlisted, e.AthleteID)
+		}
+	}
+	if len(unlisted) != 1 || unlisted[0] != 3 {
+		t.Errorf("Not in roster events for %v, want [3]", unlisted)
+	}
+}
@@ -0,0 +1,335 @@
+package race
+
+import (
+	"biathlon-prototype/configs"
+	"biathlon-prototype/events"
+	"biathlon-prototype/models"
+	"biathlon-prototype/utils"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// TeamStanding - строка итоговой таблицы эстафеты
+type TeamStanding struct {
+	Rank   int  // Место. Команды с одинаковым временем делят место
+	Tied   bool // Место разделено с другой командой
+	Team   *models.Team
+	Status models.Status // Finished - финишировал последний этап, иначе статус, на котором команда остановилась
+
+	Ranked bool          // Команда финишировала и ранжируется по времени
+	Time   time.Duration // От общего старта до финиша последнего этапа плюс штрафное время всех этапов
+	Gap    time.Duration // Отставание от победителя
+	Legs   []LegSplit    // Этапы по порядку
+}
+
+// LegSplit - один этап команды
+type LegSplit struct {
+	Leg      int
+	Athlete  *models.Athlete // nil, если участника этапа не было в событиях
+	Done     bool            // Этап закончен передачей эстафеты или финишем
+	Time     time.Duration   // Официальное время этапа (см. OfficialTime), если он закончен
+	Split    time.Duration   // От общего старта до конца этапа
+	Loops    int             // Штрафных кругов положено
+	Spares   int             // Запасных патронов дозаряжено
+	Shooting string          // Штрафные круги и запасные патроны по рубежам: "0+1 1+3"
+}
+
+// buildTeams собирает команды эстафеты по ростеру: команда - все заявки
+// с одинаковым полем team, по одному участнику на каждый из Legs этапов.
+// Возвращает configs.RosterError со всеми проблемами состава или nil
+func (r *Race) buildTeams(entries []models.Entry) error {
+	r.Teams = nil
+	r.teams = make(map[string]*models.Team)
+	var problems configs.RosterError
+	report := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+
+	for _, entry := range entries {
+		if entry.Team == "" || entry.Leg == 0 {
+			report("участник %d: в эстафете нужны команда (team) и этап (leg)", entry.AthleteID())
+			continue
+		}
+		if entry.Leg > r.Legs {
+			report("участник %d: этап %d, а в эстафете этапов %d", entry.AthleteID(), entry.Leg, r.Legs)
+			continue
+		}
+		team, exists := r.teams[entry.Team]
+		if !exists {
+			team = &models.Team{ID: len(r.Teams) + 1, Name: entry.Team, Nation: entry.Nation, Legs: make([]int, r.Legs)}
+			r.teams[entry.Team] = team
+			r.Teams = append(r.Teams, team)
+		}
+		if other := team.LegAthlete(entry.Leg); other != 0 {
+			report("участник %d: этап %d команды %s уже бежит участник %d", entry.AthleteID(), entry.Leg, team.Name, other)
+			continue
+		}
+		team.Legs[entry.Leg-1] = entry.AthleteID()
+	}
+
+	for _, team := range r.Teams {
+		for i, id := range team.Legs {
+			if id == 0 {
+				report("команда %s: не заявлен участник на этап %d", team.Name, i+1)
+			}
+		}
+	}
+	if len(problems) > 0 {
+		return problems
+	}
+	return nil
+}
+
+// teamOf возвращает команду участника эстафеты или nil
+func (r *Race) teamOf(a *models.Athlete) *models.Team {
+	if a.Entry == nil {
+		return nil
+	}
+	return r.teams[a.Entry.Team]
+}
+
+// checkRelay проверяет события, которые есть только в эстафете или
+// ограничены в ней: передачу эстафеты, запасные патроны, выстрелы сверх
+// заряженных патронов и финиш, который бывает только на последнем этапе.
+// Вызывается после проверки состояния, поэтому участник уже известен
+func (r *Race) checkRelay(athlete *models.Athlete, event events.Event) error {
+	relay := r.Format.Name() == FormatRelay
+	if relay && len(r.Teams) == 0 {
+		// Без команд этапы не связаны: следующие этапы не могут принять эстафету
+		return fmt.Errorf("для эстафеты нужен ростер с командами и этапами (SetRoster)")
+	}
+	switch event.EventID {
+	case events.EventExchange:
+		if !relay {
+			return fmt.Errorf("передача эстафеты бывает только в формате %s", FormatRelay)
+		}
+		return r.checkExchange(athlete, exchangeTarget(event))
+
+	case events.EventSpareRound:
+		if !relay {
+			return fmt.Errorf("запасные патроны есть только в формате %s", FormatRelay)
+		}
+		if stage := athlete.CurrentStage(); stage.Spares >= models.SpareRoundsPerStage {
+			return fmt.Errorf("на стрельбе %d уже дозаряжены все %d запасных патрона", stage.Stage, models.SpareRoundsPerStage)
+		}
+
+	case events.EventHitSuccessful, events.EventHitMissed:
+		if stage := athlete.CurrentStage(); relay && stage.Shots >= models.TargetsPerStage+stage.Spares {
+			return fmt.Errorf("нет заряженных патронов: на стрельбе %d выстрелов %d, запасных дозаряжено %d",
+				stage.Stage, stage.Shots, stage.Spares)
+		}
+
+	case events.EventFinished:
+		if leg := relayLeg(athlete); relay && leg > 0 && leg < r.Legs {
+			return fmt.Errorf("этап %d из %d заканчивается передачей эстафеты (событие %d)", leg, r.Legs, events.EventExchange)
+		}
+	}
+	return nil
+}
+
+// checkExchange проверяет, что from может передать эстафету участнику to:
+// тот зарегистрирован, еще не стартовал и бежит следующий этап той же команды
+func (r *Race) checkExchange(from *models.Athlete, to int) error {
+	next, exists := r.Athletes[to]
+	if !exists {
+		return fmt.Errorf("принимающий участник %d не зарегистрирован", to)
+	}
+	if !beforeStart(next.State) {
+		return fmt.Errorf("принимающий участник %d уже стартовал или выбыл (%s)", to, next.State)
+	}
+
+	team := r.teamOf(from)
+	if team == nil {
+		return fmt.Errorf("участник %d не заявлен в команду эстафеты", from.ID)
+	}
+	leg := relayLeg(from)
+	if leg >= r.Legs {
+		return fmt.Errorf("последний этап заканчивается финишем (событие %d)", events.EventFinished)
+	}
+	if want := team.LegAthlete(leg + 1); want != to {
+		return fmt.Errorf("этап %d команды %s бежит участник %d, а не %d", leg+1, team.Name, want, to)
+	}
+	return nil
+}
+
+// exchangeTarget возвращает ID принимающего участника из события 12.
+// Параметр уже проверен в CheckParams
+func exchangeTarget(event events.Event) int {
+	id, _ := strconv.Atoi(event.Params[0])
+	return id
+}
+
+// exchange передает эстафету: этап from заканчивается, to стартует в момент t
+func (r *Race) exchange(from, to *models.Athlete, t time.Time) {
+	finish, start := t, t
+	from.FinishTime = &finish
+	from.Status = models.StatusFinished
+	r.logEvent(t, events.KindExchange, from.ID, events.Exchange{Next: to.ID})
+
+	to.StartTimeActual = &start
+	to.Status = models.StatusRacing
+	to.State = models.StateRacing
+
+	exchange := models.Exchange{Time: t, Leg: relayLeg(from), From: from.ID, To: to.ID}
+	if team := r.teamOf(from); team != nil {
+		exchange.Team = team.Name
+	}
+	r.Exchanges = append(r.Exchanges, exchange)
+}
+
+// TeamStandings возвращает итоговую таблицу эстафеты. Финишировавшие
+// команды упорядочены по времени (при равном времени решает фотофиниш
+// последнего этапа), остальные идут ниже по группам статусов, затем по
+// количеству законченных этапов; внутри группы - по номеру команды
+func (r *Race) TeamStandings() []TeamStanding {
+	standings := make([]TeamStanding, 0, len(r.Teams))
+	for _, team := range r.Teams {
+		standing := TeamStanding{Team: team, Legs: make([]LegSplit, len(team.Legs))}
+		var penalty time.Duration
+		for i, id := range team.Legs {
+			split := LegSplit{Leg: i + 1, Athlete: r.Athletes[id]}
+			if a := split.Athlete; a != nil {
+				split.Time, split.Done = r.OfficialTime(a)
+				if split.Done {
+					split.Split = a.FinishTime.Sub(r.StartTime)
+				}
+				for _, stage := range a.Stages {
+					split.Loops += stage.LoopsOwed
+					split.Spares += stage.Spares
+				}
+				split.Shooting = r.ShootingString(a)
+				penalty += a.TimePenalty + a.LoopPenalty
+			}
+			standing.Legs[i] = split
+		}
+		standing.Status = teamStatus(standing.Legs)
+		if last := standing.Legs[len(standing.Legs)-1]; standing.Status == models.StatusFinished {
+			standing.Ranked = true
+			standing.Time = last.Split + penalty
+		}
+		standings = append(standings, standing)
+	}
+
+	sort.Slice(standings, func(i, j int) bool {
+		a, b := standings[i], standings[j]
+		if a.Ranked != b.Ranked {
+			return a.Ranked
+		}
+		if a.Status != b.Status {
+			return statusOrder[a.Status] < statusOrder[b.Status]
+		}
+		if a.Ranked && a.Time != b.Time {
+			return a.Time < b.Time
+		}
+		if a.Ranked && teamPhotoFinishDecides(a, b) {
+			return anchor(a).PhotoFinish < anchor(b).PhotoFinish
+		}
+		if legsA, legsB := legsDone(a), legsDone(b); legsA != legsB {
+			return legsA > legsB
+		}
+		return a.Team.ID < b.Team.ID
+	})
+
+	for i := range standings {
+		current := &standings[i]
+		current.Rank = i + 1
+		previous := TeamStanding{}
+		if i > 0 {
+			previous = standings[i-1]
+		}
+		if i > 0 && previous.Ranked && current.Ranked && previous.Time == current.Time &&
+			!teamPhotoFinishDecides(previous, *current) {
+			current.Rank = previous.Rank
+			current.Tied = true
+			standings[i-1].Tied = true
+		}
+		if current.Ranked {
+			current.Gap = current.Time - standings[0].Time
+		}
+	}
+	return standings
+}
+
+// teamStatus определяет статус команды по этапам: выбывший участник
+// останавливает команду, финиш последнего этапа - финиш команды
+func teamStatus(legs []LegSplit) models.Status {
+	for _, leg := range legs {
+		if leg.Athlete == nil {
+			continue
+		}
+		switch leg.Athlete.Status {
+		case models.StatusDisqualified, models.StatusNotFinished:
+			return leg.Athlete.Status
+		}
+	}
+	if legs[len(legs)-1].Done {
+		return models.StatusFinished
+	}
+	if first := legs[0].Athlete; first == nil || first.StartTimeActual == nil {
+		return models.StatusNotStarted
+	}
+	return models.StatusRacing
+}
+
+// anchor возвращает участника последнего этапа команды, финишировавшей эстафету
+func anchor(s TeamStanding) *models.Athlete {
+	return s.Legs[len(s.Legs)-1].Athlete
+}
+
+// teamPhotoFinishDecides сообщает, что команды с одинаковым временем
+// разделяет фотофиниш последнего этапа
+func teamPhotoFinishDecides(a, b TeamStanding) bool {
+	return anchor(a).PhotoFinish > 0 && anchor(b).PhotoFinish > 0 &&
+		anchor(a).PhotoFinish != anchor(b).PhotoFinish
+}
+
+// legsDone возвращает количество законченных этапов команды
+func legsDone(s TeamStanding) int {
+	done := 0
+	for _, leg := range s.Legs {
+		if leg.Done {
+			done++
+		}
+	}
+	return done
+}
+
+// writeTeamResults выводит таблицу эстафеты: строка на команду и по строке на этап
+func (r *Race) writeTeamResults(w io.Writer) {
+	fmt.Fprintf(w, "Команды (этапов: %d):\n", r.Legs)
+	for _, standing := range r.TeamStandings() {
+		team := standing.Team
+		fmt.Fprintf(w, "%d. Команда %d %s", standing.Rank, team.ID, team.Name)
+		if team.Nation != "" {
+			fmt.Fprintf(w, " (%s)", team.Nation)
+		}
+		fmt.Fprintf(w, " - %s\n", standing.Status)
+		if standing.Tied {
+			fmt.Fprintf(w, "   Делит место с другими командами\n")
+		}
+		if standing.Ranked {
+			fmt.Fprintf(w, "   Время команды: %s\n", utils.FormatDuration(standing.Time))
+			if standing.Gap > 0 {
+				fmt.Fprintf(w, "   Отставание: +%s\n", utils.FormatDuration(standing.Gap))
+			}
+		}
+		for _, leg := range standing.Legs {
+			fmt.Fprintf(w, "   Этап %d: Участник %d", leg.Leg, team.LegAthlete(leg.Leg))
+			if leg.Done {
+				fmt.Fprintf(w, " - %s (на этапе %s)", utils.FormatDuration(leg.Split), utils.FormatDuration(leg.Time))
+			} else if leg.Athlete != nil {
+				fmt.Fprintf(w, " - %s", leg.Athlete.Status)
+			} else {
+				fmt.Fprint(w, " - нет событий")
+			}
+			if leg.Shooting != "" {
+				fmt.Fprintf(w, ", стрельба %s", leg.Shooting)
+			}
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintln(w)
+	}
+}
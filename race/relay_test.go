@@ -0,0 +1,363 @@
+package race
+
+import (
+	"biathlon-prototype/configs"
+	"biathlon-prototype/events"
+	"biathlon-prototype/models"
+	"bytes"
+	"encoding/csv"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRelay_ExchangeAndTeamStandings(t *testing.T) {
+	r := createRelayRace(t)
+	for _, e := range []events.Event{
+		createTestEvent(events.EventAtFiringLine, "10:05:00.000", 11, "1"),
+		createTestEvent(events.EventHitSuccessful, "10:05:10.000", 11, "1"),
+		createTestEvent(events.EventHitSuccessful, "10:05:12.000", 11, "2"),
+		createTestEvent(events.EventHitSuccessful, "10:05:14.000", 11, "3"),
+		createTestEvent(events.EventHitSuccessful, "10:05:16.000", 11, "4"),
+		createTestEvent(events.EventHitMissed, "10:05:18.000", 11, "5"),
+		createTestEvent(events.EventSpareRound, "10:05:25.000", 11),
+		createTestEvent(events.EventHitSuccessful, "10:05:28.000", 11, "5"),
+		createTestEvent(events.EventLeaveFiringLine, "10:05:40.000", 11),
+
+		// Три запасных патрона не закрыли последнюю мишень - один штрафной круг
+		createTestEvent(events.EventAtFiringLine, "10:06:00.000", 21, "1"),
+		createTestEvent(events.EventHitSuccessful, "10:06:10.000", 21, "1"),
+		createTestEvent(events.EventHitSuccessful, "10:06:12.000", 21, "2"),
+		createTestEvent(events.EventHitSuccessful, "10:06:14.000", 21, "3"),
+		createTestEvent(events.EventHitMissed, "10:06:16.000", 21, "4"),
+		createTestEvent(events.EventHitMissed, "10:06:18.000", 21, "5"),
+		createTestEvent(events.EventSpareRound, "10:06:25.000", 21),
+		createTestEvent(events.EventHitSuccessful, "10:06:28.000", 21, "4"),
+		createTestEvent(events.EventSpareRound, "10:06:35.000", 21),
+		createTestEvent(events.EventHitMissed, "10:06:38.000", 21, "5"),
+		createTestEvent(events.EventSpareRound, "10:06:45.000", 21),
+		createTestEvent(events.EventHitMissed, "10:06:48.000", 21, "5"),
+		createTestEvent(events.EventLeaveFiringLine, "10:06:55.000", 21),
+		createTestEvent(events.EventEnterPenalty, "10:07:00.000", 21),
+		createTestEvent(events.EventLeavePenalty, "10:07:30.000", 21),
+
+		createTestEvent(events.EventLapFinish, "10:10:00.000", 11),
+		createTestEvent(events.EventLapFinish, "10:11:00.000", 21),
+		createTestEvent(events.EventLapFinish, "10:20:00.000", 11),
+		createTestEvent(events.EventExchange, "10:20:00.000", 11, "12"),
+		createTestEvent(events.EventLapFinish, "10:21:00.000", 21),
+		createTestEvent(events.EventExchange, "10:21:00.000", 21, "22"),
+		createTestEvent(events.EventLapFinish, "10:30:00.000", 12),
+		createTestEvent(events.EventLapFinish, "10:31:00.000", 22),
+		createTestEvent(events.EventLapFinish, "10:40:00.000", 12),
+		createTestEvent(events.EventFinished, "10:40:00.000", 12),
+		createTestEvent(events.EventLapFinish, "10:41:00.000", 22),
+		createTestEvent(events.EventFinished, "10:41:00.000", 22),
+	} {
+		if err := r.HandleEvent(e); err != nil {
+			t.Fatalf("HandleEvent(%d %d) unexpected error = %v", e.EventID, e.AthleteID, err)
+		}
+	}
+
+	if got := r.Athletes[21].Stages[0]; got.LoopsOwed != 1 || got.Spares != 3 {
+		t.Errorf("Athlete 21 stage = %d loops, %d spares, want 1 loop, 3 spares", got.LoopsOwed, got.Spares)
+	}
+	if len(r.Exchanges) != 2 || r.Exchanges[0].Team != "Norway" || r.Exchanges[0].To != 12 {
+		t.Errorf("Exchanges = %+v, want Norway 11 -> 12 first", r.Exchanges)
+	}
+	// Время этапа считается от приема эстафеты
+	if got, ok := r.OfficialTime(r.Athletes[22]); !ok || got != 20*time.Minute {
+		t.Errorf("Athlete 22 official time = %v (ok %v), want 20m", got, ok)
+	}
+
+	standings := r.TeamStandings()
+	if len(standings) != 2 {
+		t.Fatalf("TeamStandings() len = %d, want 2", len(standings))
+	}
+	winner, second := standings[0], standings[1]
+	if winner.Team.Name != "Norway" || winner.Rank != 1 || winner.Time != 40*time.Minute {
+		t.Errorf("Winner = %s rank %d time %v, want Norway rank 1 time 40m", winner.Team.Name, winner.Rank, winner.Time)
+	}
+	if second.Team.Name != "France" || second.Gap != time.Minute || second.Status != models.StatusFinished {
+		t.Errorf("Second = %s gap %v status %s, want France gap 1m Finished", second.Team.Name, second.Gap, second.Status)
+	}
+	legs := second.Legs
+	if legs[0].Split != 21*time.Minute || legs[1].Time != 20*time.Minute || legs[1].Split != 41*time.Minute {
+		t.Errorf("France legs = %+v, want splits 21m and 41m, leg 2 time 20m", legs)
+	}
+	if legs[0].Shooting != "1+3" || winner.Legs[0].Shooting != "0+1" {
+		t.Errorf("Leg 1 shooting = %q and %q, want \"1+3\" and \"0+1\"", legs[0].Shooting, winner.Legs[0].Shooting)
+	}
+
+	var report strings.Builder
+	r.WriteResults(&report)
+	for _, want := range []string{
+		"1. Команда 1 Norway (NOR) - Finished",
+		"   Время команды: 00:40:00.000",
+		"   Этап 1: Участник 21 - 00:21:00.000 (на этапе 00:21:00.000), стрельба 1+3",
+		"Рубеж 1 (стрельбище 1, позиция 2): 4/8 ●●●●○, запасных патронов: 3",
+	} {
+		if !strings.Contains(report.String(), want) {
+			t.Errorf("WriteResults() missing %q in:\n%s", want, report.String())
+		}
+	}
+
+	results := r.Results()
+	if results.Legs != 2 || len(results.Teams) != 2 || results.Teams[1].Legs[1].Split != "00:41:00.000" {
+		t.Errorf("Results() teams = %+v, want 2 teams with France leg 2 split 00:41:00.000", results.Teams)
+	}
+}
+
+func TestRelay_ShootingAndCSV(t *testing.T) {
+	r := createRelayRace(t)
+	for _, e := range []events.Event{
+		createTestEvent(events.EventAtFiringLine, "10:05:00.000", 11, "1"),
+		createTestEvent(events.EventHitMissed, "10:05:10.000", 11, "1"),
+		createTestEvent(events.EventHitSuccessful, "10:05:12.000", 11, "2"),
+		createTestEvent(events.EventHitSuccessful, "10:05:14.000", 11, "3"),
+		createTestEvent(events.EventHitSuccessful, "10:05:16.000", 11, "4"),
+		createTestEvent(events.EventHitSuccessful, "10:05:18.000", 11, "5"),
+		createTestEvent(events.EventSpareRound, "10:05:20.000", 11),
+		createTestEvent(events.EventHitSuccessful, "10:05:25.000", 11, "1"),
+		createTestEvent(events.EventLeaveFiringLine, "10:05:40.000", 11),
+		createTestEvent(events.EventLapFinish, "10:10:00.000", 11),
+		createTestEvent(events.EventLapFinish, "10:20:00.000", 11),
+		createTestEvent(events.EventExchange, "10:20:00.000", 11, "12"),
+	} {
+		if err := r.HandleEvent(e); err != nil {
+			t.Fatalf("HandleEvent(%d %d) unexpected error = %v", e.EventID, e.AthleteID, err)
+		}
+	}
+
+	// Мишень закрыта запасным патроном: штрафных кругов нет, один запасной
+	for _, standing := range r.Standings() {
+		if standing.Athlete.ID == 11 && standing.Shooting != "0+1" {
+			t.Errorf("Athlete 11 shooting = %q, want \"0+1\"", standing.Shooting)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := r.WriteCSV(&buf); err != nil {
+		t.Fatalf("WriteCSV() error = %v", err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("WriteCSV() produced invalid CSV: %v", err)
+	}
+	column := make(map[string]int, len(records[0]))
+	for i, name := range records[0] {
+		column[name] = i
+	}
+	for _, name := range relayColumns {
+		if _, ok := column[name]; !ok {
+			t.Fatalf("Expected column %q in header %v", name, records[0])
+		}
+	}
+	for _, record := range records[1:] {
+		if record[column["athlete_id"]] != "11" {
+			continue
+		}
+		if record[column["team_id"]] != "1" || record[column["leg"]] != "1" ||
+			record[column["leg_split"]] != "00:20:00.000" || record[column["spares"]] != "1" ||
+			record[column["misses"]] != "0+1" {
+			t.Errorf("Athlete 11 row = %v, want Norway leg 1 split 00:20:00.000, 1 spare, 0+1", record)
+		}
+	}
+}
+
+func TestRelay_Rejections(t *testing.T) {
+	testCases := []struct {
+		name  string
+		setup []events.Event
+		event events.Event
+		kind  ViolationKind
+	}{
+		{
+			name:  "Exchange before all laps",
+			event: createTestEvent(events.EventExchange, "10:10:00.000", 11, "12"),
+			kind:  ViolationOrder,
+		},
+		{
+			name: "Exchange to another team",
+			setup: []events.Event{
+				createTestEvent(events.EventLapFinish, "10:10:00.000", 11),
+				createTestEvent(events.EventLapFinish, "10:20:00.000", 11),
+			},
+			event: createTestEvent(events.EventExchange, "10:20:00.000", 11, "22"),
+			kind:  ViolationOrder,
+		},
+		{
+			name:  "Exchange without receiver",
+			event: createTestEvent(events.EventExchange, "10:20:00.000", 11),
+			kind:  ViolationBadParams,
+		},
+		{
+			name: "Finish on first leg",
+			setup: []events.Event{
+				createTestEvent(events.EventLapFinish, "10:10:00.000", 11),
+				createTestEvent(events.EventLapFinish, "10:20:00.000", 11),
+			},
+			event: createTestEvent(events.EventFinished, "10:20:00.000", 11),
+			kind:  ViolationOrder,
+		},
+		{
+			name: "Sixth shot without spare round",
+			setup: append([]events.Event{createTestEvent(events.EventAtFiringLine, "10:05:00.000", 11, "1")},
+				fiveMisses(11)...),
+			event: createTestEvent(events.EventHitSuccessful, "10:05:30.000", 11, "1"),
+			kind:  ViolationOrder,
+		},
+		{
+			name: "Fourth spare round",
+			setup: []events.Event{
+				createTestEvent(events.EventAtFiringLine, "10:05:00.000", 11, "1"),
+				createTestEvent(events.EventSpareRound, "10:05:20.000", 11),
+				createTestEvent(events.EventSpareRound, "10:05:25.000", 11),
+				createTestEvent(events.EventSpareRound, "10:05:30.000", 11),
+			},
+			event: createTestEvent(events.EventSpareRound, "10:05:35.000", 11),
+			kind:  ViolationOrder,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := createRelayRace(t)
+			for _, e := range tc.setup {
+				if err := r.HandleEvent(e); err != nil {
+					t.Fatalf("setup HandleEvent() unexpected error = %v", err)
+				}
+			}
+
+			err := r.HandleEvent(tc.event)
+			violation, ok := err.(Violation)
+			if !ok {
+				t.Fatalf("HandleEvent() error = %v, want Violation", err)
+			}
+			if violation.Kind != tc.kind {
+				t.Errorf("Violation kind = %s, want %s (%s)", violation.Kind, tc.kind, violation.Reason)
+			}
+		})
+	}
+
+	t.Run("Spare round outside relay", func(t *testing.T) {
+		r := createTestRaceWithFormat(FormatMassStart)
+		r.Output = io.Discard
+		registerAthlete(r, 1)
+		r.HandleEvent(createTestEvent(events.EventAtStartLine, "09:59:00.000", 1))
+		r.HandleEvent(createTestEvent(events.EventStart, "10:00:00.000", 1))
+		r.HandleEvent(createTestEvent(events.EventAtFiringLine, "10:05:00.000", 1, "1"))
+
+		if err := r.HandleEvent(createTestEvent(events.EventSpareRound, "10:05:20.000", 1)); err == nil {
+			t.Error("HandleEvent() expected violation for spare round in mass start")
+		}
+	})
+
+	t.Run("Relay without roster", func(t *testing.T) {
+		r, err := NewRace(relayConfig())
+		if err != nil {
+			t.Fatalf("NewRace() error = %v", err)
+		}
+		r.Output = io.Discard
+
+		err = r.HandleEvent(createTestEvent(events.EventRegister, "09:00:00.000", 11))
+		if violation, ok := err.(Violation); !ok || violation.Kind != ViolationOrder {
+			t.Fatalf("HandleEvent() error = %v, want order violation", err)
+		}
+		if len(r.Athletes) != 0 {
+			t.Errorf("Athletes = %d, want 0 without roster", len(r.Athletes))
+		}
+	})
+}
+
+func TestSetRoster_RelayTeams(t *testing.T) {
+	testCases := []struct {
+		name    string
+		entries []models.Entry
+		wantErr string
+	}{
+		{
+			name:    "Missing leg",
+			entries: []models.Entry{{ID: 11, Name: "A", Team: "Norway", Leg: 1}},
+			wantErr: "команда Norway: не заявлен участник на этап 2",
+		},
+		{
+			name: "Leg taken",
+			entries: []models.Entry{
+				{ID: 11, Name: "A", Team: "Norway", Leg: 1},
+				{ID: 12, Name: "B", Team: "Norway", Leg: 1},
+			},
+			wantErr: "этап 1 команды Norway уже бежит участник 11",
+		},
+		{
+			name:    "Leg out of range",
+			entries: []models.Entry{{ID: 11, Name: "A", Team: "Norway", Leg: 3}},
+			wantErr: "этап 3, а в эстафете этапов 2",
+		},
+		{
+			name:    "No team",
+			entries: []models.Entry{{ID: 11, Name: "A", Leg: 1}},
+			wantErr: "нужны команда (team) и этап (leg)",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r, _ := NewRace(relayConfig())
+			err := r.SetRoster(tc.entries)
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("SetRoster() error = %v, want containing %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func relayConfig() configs.Config {
+	return configs.Config{
+		Laps:        2,
+		LapLen:      2500,
+		PenaltyLen:  150,
+		FiringLines: 1,
+		Start:       "10:00:00",
+		StartDelta:  "00:01:00",
+		Format:      FormatRelay,
+		Legs:        2,
+	}
+}
+
+// createRelayRace создает эстафету из двух команд по два этапа;
+// первые этапы (11 и 21) уже стартовали в 10:00
+func createRelayRace(t *testing.T) *Race {
+	t.Helper()
+	r, err := NewRace(relayConfig())
+	if err != nil {
+		t.Fatalf("NewRace() error = %v", err)
+	}
+	r.Output = io.Discard
+	err = r.SetRoster([]models.Entry{
+		{ID: 11, Name: "A", Nation: "NOR", Team: "Norway", Leg: 1},
+		{ID: 12, Name: "B", Nation: "NOR", Team: "Norway", Leg: 2},
+		{ID: 21, Name: "C", Nation: "FRA", Team: "France", Leg: 1},
+		{ID: 22, Name: "D", Nation: "FRA", Team: "France", Leg: 2},
+	})
+	if err != nil {
+		t.Fatalf("SetRoster() error = %v", err)
+	}
+	for _, id := range []int{11, 12, 21, 22} {
+		registerAthlete(r, id)
+	}
+	for _, id := range []int{11, 21} {
+		r.HandleEvent(createTestEvent(events.EventAtStartLine, "09:59:00.000", id))
+		r.HandleEvent(createTestEvent(events.EventStart, "10:00:00.000", id))
+	}
+	return r
+}
+
+func fiveMisses(id int) []events.Event {
+	misses := make([]events.Event, models.TargetsPerStage)
+	for i := range misses {
+		misses[i] = createTestEvent(events.EventHitMissed, "10:05:10.000", id, string(rune('1'+i)))
+	}
+	return misses
+}
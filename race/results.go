@@ -1,51 +1,71 @@
 package race
 
 import (
+	"biathlon-prototype/configs"
 	"biathlon-prototype/models"
 	"biathlon-prototype/utils"
 	"fmt"
+	"io"
 	"math"
-	"sort"
+	"os"
+	"strconv"
+	"strings"
 )
 
+// PrintResults выводит итоговый отчет в stdout
 func (r *Race) PrintResults() {
-	// Рассчитываем дополнительную статистику перед выводом
-	r.CalculateStats()
-
-	var results []*models.Athlete
+	r.WriteResults(os.Stdout)
+}
 
-	// Собираем всех участников
-	for _, athlete := range r.Athletes {
-		results = append(results, athlete)
+// WriteResults выводит итоговый отчет в w
+func (r *Race) WriteResults(w io.Writer) {
+	fmt.Fprintln(w, "\n🏁 Итоговый отчет:")
+	fmt.Fprintf(w, "Места определены по: %s\n", r.Format.RankingBasis())
+	if len(r.Config.LapLens) > 0 {
+		fmt.Fprintf(w, "Круги: %s м\n", joinInts(r.Config.LapLens, " + "))
 	}
-
-	// Сортируем по статусу и времени
-	sort.Slice(results, func(i, j int) bool {
-		if results[i].Status == results[j].Status {
-			if results[i].FinishTime != nil && results[j].FinishTime != nil {
-				return results[i].FinishTime.Before(*results[j].FinishTime)
-			}
-			return results[i].ID < results[j].ID
+	if len(r.Config.Stages) > 0 {
+		positions := make([]string, len(r.Config.Stages))
+		for i, position := range r.Config.Stages {
+			positions[i] = configs.PositionName(position)
+		}
+		fmt.Fprintf(w, "Огневые рубежи: %s\n", strings.Join(positions, ", "))
+	}
+	if r.Format.Name() == FormatRelay {
+		r.writeTeamResults(w)
+		fmt.Fprintln(w, "Участники (время этапа):")
+	}
+	for _, standing := range r.Standings() {
+		athlete := standing.Athlete
+		if athlete.Entry != nil {
+			fmt.Fprintf(w, "%d. Участник %d (%s) - %s\n", standing.Rank, athlete.ID, athlete.Entry, athlete.Status)
+		} else {
+			fmt.Fprintf(w, "%d. Участник %d - %s\n", standing.Rank, athlete.ID, athlete.Status)
+		}
+		if standing.Tied {
+			fmt.Fprintf(w, "   Делит место с другими участниками\n")
+		}
+		if athlete.PhotoFinish > 0 {
+			fmt.Fprintf(w, "   Фотофиниш: %d\n", athlete.PhotoFinish)
+		}
+		if standing.Ranked && standing.Gap > 0 {
+			fmt.Fprintf(w, "   Отставание: +%s\n", utils.FormatDuration(standing.Gap))
 		}
-		return results[i].Status < results[j].Status
-	})
-
-	fmt.Println("\n🏁 Итоговый отчет:")
-	for pos, athlete := range results {
-		fmt.Printf("%d. Участник %d - %s\n", pos+1, athlete.ID, athlete.Status)
 
 		// Основная информация о времени
+		if standing.Ranked {
+			fmt.Fprintf(w, "   Официальное время: %s\n", utils.FormatDuration(standing.OfficialTime))
+		}
 		if athlete.StartTimeActual != nil {
 			if athlete.FinishTime != nil {
-				totalTime := athlete.FinishTime.Sub(*athlete.StartTimeActual)
-				fmt.Printf("   Общее время: %s\n", utils.FormatDuration(totalTime))
+				fmt.Fprintf(w, "   Общее время: %s (чистое, от фактического старта)\n", utils.FormatDuration(standing.NetTime))
 			}
 
 			// Время кругов с расчетом скорости
 			for i, lapTime := range athlete.LapTimes {
 				if i < len(athlete.LapTimes) {
-					speed := float64(r.Config.LapLen) / lapTime.Seconds()
-					fmt.Printf("   Круг %d: %s (%.2f м/с)\n",
+					speed := float64(r.Config.LapLength(i+1)) / lapTime.Seconds()
+					fmt.Fprintf(w, "   Круг %d: %s (%.2f м/с)\n",
 						i+1, utils.FormatDuration(lapTime), speed)
 				}
 			}
@@ -54,26 +74,83 @@ func (r *Race) PrintResults() {
 			totalPenaltySeconds := 0
 			for i, penaltyTime := range athlete.PenaltyTimes {
 				if penaltyTime > 0 {
-					speed := float64(r.Config.PenaltyLen) / penaltyTime.Seconds()
+					speed := float64(r.penaltyLength(athlete, i)) / penaltyTime.Seconds()
 					totalPenaltySeconds += int(penaltyTime.Seconds())
-					fmt.Printf("   Штраф %d: %s (%.2f м/с)\n",
+					fmt.Fprintf(w, "   Штраф %d: %s (%.2f м/с)\n",
 						i+1, utils.FormatDuration(penaltyTime), math.Round(speed*100)/100)
 				}
 			}
 			if totalPenaltySeconds > 0 {
-				fmt.Printf("   Общее штрафное время: %d сек\n", totalPenaltySeconds)
+				fmt.Fprintf(w, "   Общее штрафное время: %d сек\n", totalPenaltySeconds)
+			}
+			if standing.LoopsOwed > 0 || standing.LoopsServed > 0 {
+				fmt.Fprintf(w, "   Штрафные круги: пройдено %d из %d\n", standing.LoopsServed, standing.LoopsOwed)
+			}
+			if standing.SkippedLoops > 0 {
+				fmt.Fprintf(w, "   Внимание: пропущено штрафных кругов: %d\n", standing.SkippedLoops)
+			}
+			if standing.Suspected > 0 {
+				fmt.Fprintf(w, "   Внимание: возможно пропущено штрафных кругов (по времени): %d\n", standing.Suspected)
+			}
+			if standing.LoopPenalty > 0 {
+				fmt.Fprintf(w, "   Штраф за пропущенные круги: %s\n", utils.FormatDuration(standing.LoopPenalty))
+			}
+			if athlete.TimePenalty > 0 {
+				fmt.Fprintf(w, "   Штраф за промахи: %s\n", utils.FormatDuration(athlete.TimePenalty))
 			}
 		}
 
 		// Расширенная статистика
-		fmt.Printf("   Общая дистанция: %d м\n", athlete.TotalDistance)
+		fmt.Fprintf(w, "   Общая дистанция: %d м\n", athlete.TotalDistance)
 		if athlete.AvgSpeed > 0 {
-			fmt.Printf("   Средняя скорость: %.2f м/с\n", athlete.AvgSpeed)
+			fmt.Fprintf(w, "   Средняя скорость: %.2f м/с\n", athlete.AvgSpeed)
 		}
 		if athlete.Shots > 0 {
-			fmt.Printf("   Точность стрельбы: %.1f%%\n", athlete.Accuracy)
+			fmt.Fprintf(w, "   Точность стрельбы: %.1f%%\n", athlete.Accuracy)
+		}
+		for _, stage := range athlete.Stages {
+			fmt.Fprintf(w, "   Рубеж %d (%s): %d/%d %s", stage.Stage, stageDescription(stage), stage.Hits, stage.Shots, stage.TargetCard())
+			if stage.Spares > 0 {
+				fmt.Fprintf(w, ", запасных патронов: %d", stage.Spares)
+			}
+			if rangeTime := stage.RangeTime(); rangeTime > 0 {
+				fmt.Fprintf(w, ", на рубеже %s", utils.FormatDuration(rangeTime))
+			}
+			fmt.Fprintln(w)
+		}
+		for _, position := range []string{configs.PositionProne, configs.PositionStanding} {
+			if shots, hits := athlete.PositionShooting(position); shots > 0 {
+				fmt.Fprintf(w, "   Стрельба %s: %d/%d (%.1f%%)\n", configs.PositionName(position),
+					hits, shots, float64(hits)/float64(shots)*100)
+			}
+		}
+		fmt.Fprintf(w, "   Стрельба: %d/%d попаданий", athlete.Hits, athlete.Shots)
+		if standing.Shooting != "" && r.Format.Name() == FormatRelay {
+			fmt.Fprintf(w, ", штрафные круги и запасные патроны: %s", standing.Shooting)
+		} else if standing.Shooting != "" {
+			fmt.Fprintf(w, ", промахи: %s", standing.Shooting)
 		}
-		fmt.Printf("   Стрельба: %d/%d попаданий\n\n",
-			athlete.Hits, athlete.Shots)
+		fmt.Fprint(w, "\n\n")
+	}
+}
+
+func joinInts(values []int, separator string) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = strconv.Itoa(v)
+	}
+	return strings.Join(parts, separator)
+}
+
+// stageDescription описывает положение и место стрельбы: "лежа, стрельбище 1, позиция 12"
+func stageDescription(stage models.ShootingStage) string {
+	parts := make([]string, 0, 3)
+	if stage.Position != "" {
+		parts = append(parts, configs.PositionName(stage.Position))
+	}
+	parts = append(parts, fmt.Sprintf("стрельбище %d", stage.Line))
+	if stage.Lane > 0 {
+		parts = append(parts, fmt.Sprintf("позиция %d", stage.Lane))
 	}
+	return strings.Join(parts, ", ")
 }
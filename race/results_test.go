@@ -6,7 +6,6 @@ import (
 	"bytes"
 	"io"
 	"os"
-	"sort"
 	"strings"
 	"testing"
 	"time"
@@ -46,6 +45,8 @@ func TestPrintResults(t *testing.T) {
 		"Общее время:",
 		"Круг 1:",
 		"Общая дистанция: 8000 м",
+		"Рубеж 1 (стрельбище 1): 4/5 ●●○●●",
+		"Стрельба: 8/10 попаданий, промахи: 1+1 = 2",
 		"2. Участник 2 - Finished",
 		"3. Участник 3 - Disqualified",
 	}
@@ -59,9 +60,7 @@ func TestPrintResults(t *testing.T) {
 
 func TestResultsSorting(t *testing.T) {
 	r := createTestRaceWithAthletes()
-	r.CalculateStats()
-
-	results := r.getSortedResults()
+	results := r.Standings()
 
 	// Проверяем порядок результатов
 	if len(results) != 3 {
@@ -69,13 +68,13 @@ func TestResultsSorting(t *testing.T) {
 	}
 
 	// Первый должен быть участник 1 (финишировал первым)
-	if results[0].ID != 1 {
-		t.Errorf("Expected first place to be athlete 1, got %d", results[0].ID)
+	if results[0].Athlete.ID != 1 {
+		t.Errorf("Expected first place to be athlete 1, got %d", results[0].Athlete.ID)
 	}
 
 	// Последний должен быть участник 3 (дисквалифицирован)
-	if results[2].ID != 3 {
-		t.Errorf("Expected last place to be athlete 3, got %d", results[2].ID)
+	if results[2].Athlete.ID != 3 {
+		t.Errorf("Expected last place to be athlete 3, got %d", results[2].Athlete.ID)
 	}
 }
 
@@ -101,6 +100,11 @@ func createTestRaceWithAthletes() *Race {
 		PenaltyTimes:    []time.Duration{2 * time.Minute},
 		Shots:           10,
 		Hits:            8,
+		Stages: []models.ShootingStage{
+			{Stage: 1, Line: 1, Shots: 5, Hits: 4,
+				Targets: map[int]bool{1: true, 2: true, 3: false, 4: true, 5: true}},
+			{Stage: 2, Line: 2, Shots: 5, Hits: 4},
+		},
 	}
 	r.Athletes[1] = athlete1
 
@@ -114,6 +118,10 @@ func createTestRaceWithAthletes() *Race {
 		PenaltyTimes:    []time.Duration{3 * time.Minute},
 		Shots:           10,
 		Hits:            7,
+		Stages: []models.ShootingStage{
+			{Stage: 1, Line: 1, Shots: 5, Hits: 3},
+			{Stage: 2, Line: 2, Shots: 5, Hits: 4},
+		},
 	}
 	r.Athletes[2] = athlete2
 
@@ -129,26 +137,6 @@ func createTestRaceWithAthletes() *Race {
 	return r
 }
 
-// Вспомогательная функция для получения отсортированных результатов
-func (r *Race) getSortedResults() []*models.Athlete {
-	var results []*models.Athlete
-	for _, athlete := range r.Athletes {
-		results = append(results, athlete)
-	}
-
-	sort.Slice(results, func(i, j int) bool {
-		if results[i].Status == results[j].Status {
-			if results[i].FinishTime != nil && results[j].FinishTime != nil {
-				return results[i].FinishTime.Before(*results[j].FinishTime)
-			}
-			return results[i].ID < results[j].ID
-		}
-		return results[i].Status < results[j].Status
-	})
-
-	return results
-}
-
 func timePtr(t time.Time) *time.Time {
 	return &t
 }
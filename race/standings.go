@@ -0,0 +1,156 @@
+package race
+
+import (
+	"biathlon-prototype/models"
+	"sort"
+	"time"
+)
+
+// Standing - строка итоговой таблицы
+type Standing struct {
+	Rank    int  // Место. Участники с одинаковым временем делят место, следующее место пропускается
+	Tied    bool // Место разделено с другим участником
+	Athlete *models.Athlete
+	Status  models.Status
+
+	Ranked       bool          // Участник финишировал и ранжируется по официальному времени
+	OfficialTime time.Duration // Время, по которому ранжируется участник (см. OfficialTime)
+	NetTime      time.Duration // Время от фактического старта до финиша без штрафов (см. NetTime)
+	Gap          time.Duration // Отставание от лидера по официальному времени
+
+	Laps         int           // Пройдено кругов
+	PenaltyLoops time.Duration // Время на штрафных кругах
+	LoopsOwed    int           // Штрафных кругов положено за промахи
+	LoopsServed  int           // Штрафных кругов пройдено
+	SkippedLoops int           // Положенных штрафных кругов не пройдено
+	Suspected    int           // Штрафных кругов, возможно не пройденных (по времени)
+	LoopPenalty  time.Duration // Штрафное время за непройденные штрафные круги
+	TimePenalty  time.Duration // Штрафное время за промахи
+	Shots        int
+	Hits         int
+	Shooting     string // Стрельба по рубежам, см. Race.ShootingString
+}
+
+// statusOrder задает порядок групп в таблице: финишировавшие выше всех,
+// дисквалифицированные ниже всех
+var statusOrder = map[models.Status]int{
+	models.StatusFinished:     0,
+	models.StatusRacing:       1,
+	models.StatusNotFinished:  2,
+	models.StatusNotStarted:   3,
+	models.StatusDisqualified: 4,
+}
+
+// Standings возвращает итоговую таблицу по правилам формата гонки.
+// Ранжирование всегда идет по официальному времени (см. Format.RankingBasis).
+// Финишировавшие упорядочены по времени, остальные идут ниже по группам
+// статусов; при равном времени в форматах с порядком финиша решает фотофиниш
+// (параметр события 33), иначе участники делят место; внутри группы - по ID участника
+func (r *Race) Standings() []Standing {
+	r.CalculateStats()
+
+	standings := make([]Standing, 0, len(r.Athletes))
+	for _, a := range r.Athletes {
+		standing := Standing{
+			Athlete:      a,
+			Status:       a.Status,
+			Laps:         len(a.LapTimes),
+			TimePenalty:  a.TimePenalty,
+			LoopsOwed:    a.LoopsOwed(),
+			LoopsServed:  a.LoopsServed(),
+			SkippedLoops: a.SkippedLoops(),
+			Suspected:    a.SuspectedLoops(),
+			LoopPenalty:  a.LoopPenalty,
+			Shots:        a.Shots,
+			Hits:         a.Hits,
+			Shooting:     r.ShootingString(a),
+		}
+		standing.OfficialTime, standing.Ranked = r.OfficialTime(a)
+		standing.NetTime, _ = r.NetTime(a)
+		for _, penaltyTime := range a.PenaltyTimes {
+			standing.PenaltyLoops += penaltyTime
+		}
+		standings = append(standings, standing)
+	}
+
+	sort.Slice(standings, func(i, j int) bool {
+		a, b := standings[i], standings[j]
+		if a.Ranked != b.Ranked {
+			return a.Ranked
+		}
+		if a.Status != b.Status {
+			return statusOrder[a.Status] < statusOrder[b.Status]
+		}
+		if a.Ranked && a.OfficialTime != b.OfficialTime {
+			return a.OfficialTime < b.OfficialTime
+		}
+		if a.Ranked && r.photoFinishDecides(a, b) {
+			return a.Athlete.PhotoFinish < b.Athlete.PhotoFinish
+		}
+		return a.Athlete.ID < b.Athlete.ID
+	})
+
+	for i := range standings {
+		current := &standings[i]
+		current.Rank = i + 1
+		if i > 0 && r.sameResult(standings[i-1], *current) {
+			current.Rank = standings[i-1].Rank
+			current.Tied = true
+			standings[i-1].Tied = true
+		}
+		if current.Ranked {
+			current.Gap = current.OfficialTime - standings[0].OfficialTime
+		}
+	}
+	return standings
+}
+
+// ShootingString записывает стрельбу участника по рубежам так, как принято
+// в протоколах формата: промахи ("1+0+2+0 = 3"), а в эстафете - штрафные
+// круги и запасные патроны ("0+1 1+3"), потому что мишень, закрытая
+// запасным патроном, промахом не считается
+func (r *Race) ShootingString(a *models.Athlete) string {
+	if r.Format.Name() == FormatRelay {
+		return models.RelayShootingString(a.Stages)
+	}
+	return models.ShootingString(a.Stages)
+}
+
+// sameResult сообщает, что два участника показали одинаковый результат
+func (r *Race) sameResult(a, b Standing) bool {
+	return a.Ranked && b.Ranked && a.OfficialTime == b.OfficialTime && !r.photoFinishDecides(a, b)
+}
+
+// photoFinishDecides сообщает, что участников с одинаковым временем
+// разделяет фотофиниш: места по нему есть у обоих и они разные. Фотофиниш
+// учитывается только там, где места определяются порядком финиша, то есть
+// без раздельного старта
+func (r *Race) photoFinishDecides(a, b Standing) bool {
+	return r.Format.StartMode() != StartInterval &&
+		a.Athlete.PhotoFinish > 0 && b.Athlete.PhotoFinish > 0 &&
+		a.Athlete.PhotoFinish != b.Athlete.PhotoFinish
+}
+
+// OfficialTime возвращает официальное время участника: от момента,
+// заданного форматом гонки (Format.OfficialStart), до финиша плюс
+// штрафное время. ok == false, если участник не финишировал
+func (r *Race) OfficialTime(a *models.Athlete) (t time.Duration, ok bool) {
+	if a.Status != models.StatusFinished || a.FinishTime == nil {
+		return 0, false
+	}
+	start := r.Format.OfficialStart(r, a)
+	if start.IsZero() {
+		return 0, false
+	}
+	return a.FinishTime.Sub(start) + a.TimePenalty + a.LoopPenalty, true
+}
+
+// NetTime возвращает чистое время участника на дистанции: от фактического
+// старта до финиша, без штрафного времени. ok == false, если участник
+// не стартовал или не финишировал
+func (r *Race) NetTime(a *models.Athlete) (t time.Duration, ok bool) {
+	if a.StartTimeActual == nil || a.FinishTime == nil {
+		return 0, false
+	}
+	return a.FinishTime.Sub(*a.StartTimeActual), true
+}
@@ -0,0 +1,231 @@
+package race
+
+import (
+	"biathlon-prototype/configs"
+	"biathlon-prototype/events"
+	"biathlon-prototype/models"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestStandings_RanksAndGaps(t *testing.T) {
+	r := createTestRaceWithAthletes()
+
+	standings := r.Standings()
+	if len(standings) != 3 {
+		t.Fatalf("Expected 3 standings, got %d", len(standings))
+	}
+
+	want := []struct {
+		id     int
+		rank   int
+		ranked bool
+		time   time.Duration
+		gap    time.Duration
+	}{
+		{id: 1, rank: 1, ranked: true, time: 90 * time.Minute, gap: 0},
+		{id: 2, rank: 2, ranked: true, time: 94 * time.Minute, gap: 4 * time.Minute},
+		{id: 3, rank: 3, ranked: false},
+	}
+
+	for i, w := range want {
+		got := standings[i]
+		if got.Athlete.ID != w.id || got.Rank != w.rank || got.Ranked != w.ranked {
+			t.Errorf("standings[%d] = athlete %d rank %d ranked %v, want athlete %d rank %d ranked %v",
+				i, got.Athlete.ID, got.Rank, got.Ranked, w.id, w.rank, w.ranked)
+		}
+		if got.OfficialTime != w.time || got.Gap != w.gap {
+			t.Errorf("standings[%d] time/gap = %v/%v, want %v/%v", i, got.OfficialTime, got.Gap, w.time, w.gap)
+		}
+		if got.Tied {
+			t.Errorf("standings[%d] unexpectedly tied", i)
+		}
+	}
+
+	if standings[0].PenaltyLoops != 2*time.Minute {
+		t.Errorf("PenaltyLoops = %v, want 2m0s", standings[0].PenaltyLoops)
+	}
+}
+
+func TestStandings_Ties(t *testing.T) {
+	r := createTestRaceWithAthletes()
+
+	// Участник 4 показал то же время, что и победитель
+	r.Athletes[4] = &models.Athlete{
+		ID:              4,
+		Status:          models.StatusFinished,
+		StartTimeActual: timePtr(time.Date(0, 1, 1, 10, 2, 0, 0, time.UTC)),
+		FinishTime:      timePtr(time.Date(0, 1, 1, 11, 32, 0, 0, time.UTC)),
+	}
+
+	standings := r.Standings()
+	ranks := make(map[int]Standing)
+	for _, s := range standings {
+		ranks[s.Athlete.ID] = s
+	}
+
+	if ranks[1].Rank != 1 || ranks[4].Rank != 1 {
+		t.Errorf("Expected athletes 1 and 4 to share rank 1, got %d and %d", ranks[1].Rank, ranks[4].Rank)
+	}
+	if !ranks[1].Tied || !ranks[4].Tied {
+		t.Error("Expected athletes 1 and 4 to be marked as tied")
+	}
+	if ranks[4].Gap != 0 {
+		t.Errorf("Expected zero gap for tied leader, got %v", ranks[4].Gap)
+	}
+
+	// После двух первых мест следующее - третье
+	if ranks[2].Rank != 3 || ranks[2].Tied {
+		t.Errorf("Expected athlete 2 at untied rank 3, got %d (tied %v)", ranks[2].Rank, ranks[2].Tied)
+	}
+
+	// При равном времени выше стоит участник с меньшим ID
+	if standings[0].Athlete.ID != 1 || standings[1].Athlete.ID != 4 {
+		t.Errorf("Expected tied athletes in ID order, got %d and %d", standings[0].Athlete.ID, standings[1].Athlete.ID)
+	}
+}
+
+func TestStandings_NotFinishedAreNotTied(t *testing.T) {
+	r := createTestRace()
+	r.Athletes[1] = &models.Athlete{ID: 1, Status: models.StatusNotFinished}
+	r.Athletes[2] = &models.Athlete{ID: 2, Status: models.StatusNotFinished}
+
+	standings := r.Standings()
+	if standings[0].Rank != 1 || standings[1].Rank != 2 {
+		t.Errorf("Expected ranks 1 and 2, got %d and %d", standings[0].Rank, standings[1].Rank)
+	}
+	if standings[0].Tied || standings[1].Tied {
+		t.Error("Expected non-finishers not to be tied")
+	}
+}
+
+func TestOfficialAndNetTime(t *testing.T) {
+	planned := time.Date(0, 1, 1, 10, 1, 0, 0, time.UTC)
+	actual := planned.Add(20 * time.Second) // Стартовал с опозданием
+	finish := planned.Add(50 * time.Minute)
+
+	newAthlete := func() *models.Athlete {
+		return &models.Athlete{
+			ID:               1,
+			Status:           models.StatusFinished,
+			StartTimePlanned: planned,
+			StartTimeActual:  timePtr(actual),
+			FinishTime:       timePtr(finish),
+			TimePenalty:      2 * time.Minute,
+		}
+	}
+
+	testCases := []struct {
+		format   string
+		official time.Duration
+	}{
+		// Опоздание на старт входит в официальное время
+		{format: FormatSprint, official: 52 * time.Minute},
+		{format: FormatIndividual, official: 52 * time.Minute},
+		// Время считается от старта гонки (10:00), гандикап входит в результат
+		{format: FormatPursuit, official: 53 * time.Minute},
+		{format: FormatMassStart, official: 53 * time.Minute},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.format, func(t *testing.T) {
+			r := createTestRaceWithFormat(tc.format)
+			a := newAthlete()
+
+			official, ok := r.OfficialTime(a)
+			if !ok || official != tc.official {
+				t.Errorf("OfficialTime() = %v, %v, want %v, true", official, ok, tc.official)
+			}
+
+			net, ok := r.NetTime(a)
+			if want := finish.Sub(actual); !ok || net != want {
+				t.Errorf("NetTime() = %v, %v, want %v, true", net, ok, want)
+			}
+
+			if r.Format.RankingBasis() == "" {
+				t.Error("Expected ranking basis description")
+			}
+		})
+	}
+}
+
+func TestOfficialTime_NotFinished(t *testing.T) {
+	r := createTestRace()
+	a := &models.Athlete{
+		ID:              1,
+		Status:          models.StatusNotFinished,
+		StartTimeActual: timePtr(time.Date(0, 1, 1, 10, 0, 0, 0, time.UTC)),
+	}
+
+	if _, ok := r.OfficialTime(a); ok {
+		t.Error("Expected no official time for athlete who did not finish")
+	}
+	if _, ok := r.NetTime(a); ok {
+		t.Error("Expected no net time for athlete who did not finish")
+	}
+}
+
+func TestShootingString(t *testing.T) {
+	tests := []struct {
+		name   string
+		stages []models.ShootingStage
+		want   string
+	}{
+		{"No shooting", nil, ""},
+		{"One stage", []models.ShootingStage{{Shots: 5, Hits: 5}}, "0 = 0"},
+		{"Four stages", []models.ShootingStage{
+			{Shots: 5, Hits: 4}, {Shots: 5, Hits: 5}, {Shots: 5, Hits: 3}, {Shots: 5, Hits: 5},
+		}, "1+0+2+0 = 3"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := models.ShootingString(tt.stages); got != tt.want {
+				t.Errorf("ShootingString() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStandings_SkippedLoopCharged(t *testing.T) {
+	r := createTestRace()
+	r.Output = io.Discard
+	registerAndStartAthlete(r, 1)
+	registerAndStartAthlete(r, 2)
+
+	// Оба промахнулись один раз; первый прошел штрафной круг, второй - нет и финишировал раньше
+	for _, e := range []events.Event{
+		createTestEvent(events.EventAtFiringLine, "10:10:00.000", 1, "1"),
+		createTestEvent(events.EventHitMissed, "10:10:10.000", 1, "1"),
+		createTestEvent(events.EventLeaveFiringLine, "10:10:40.000", 1),
+		createTestEvent(events.EventEnterPenalty, "10:11:00.000", 1),
+		createTestEvent(events.EventLeavePenalty, "10:11:30.000", 1),
+		createTestEvent(events.EventAtFiringLine, "10:10:00.000", 2, "1"),
+		createTestEvent(events.EventHitMissed, "10:10:10.000", 2, "1"),
+		createTestEvent(events.EventLeaveFiringLine, "10:10:40.000", 2),
+		createTestEvent(events.EventLapFinish, "10:30:30.000", 1),
+		createTestEvent(events.EventLapFinish, "10:30:00.000", 2),
+		createTestEvent(events.EventLapFinish, "11:00:30.000", 1),
+		createTestEvent(events.EventLapFinish, "11:00:00.000", 2),
+		createTestEvent(events.EventLapFinish, "11:30:30.000", 1),
+		createTestEvent(events.EventFinished, "11:30:31.000", 1),
+		createTestEvent(events.EventLapFinish, "11:30:00.000", 2),
+		createTestEvent(events.EventFinished, "11:30:01.000", 2),
+	} {
+		if err := r.HandleEvent(e); err != nil {
+			t.Fatalf("HandleEvent(%d %d) unexpected error = %v", e.EventID, e.AthleteID, err)
+		}
+	}
+
+	standings := r.Standings()
+	first, second := standings[0], standings[1]
+	if first.Athlete.ID != 1 || first.OfficialTime != 90*time.Minute+31*time.Second {
+		t.Errorf("First = athlete %d in %v, want athlete 1 in 1h30m31s", first.Athlete.ID, first.OfficialTime)
+	}
+	want := 90*time.Minute + time.Second + configs.DefaultSkippedLoopPenalty
+	if second.Athlete.ID != 2 || second.SkippedLoops != 1 || second.OfficialTime != want {
+		t.Errorf("Second = athlete %d in %v with %d skipped, want athlete 2 in %v with 1 skipped",
+			second.Athlete.ID, second.OfficialTime, second.SkippedLoops, want)
+	}
+}
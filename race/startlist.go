@@ -0,0 +1,209 @@
+package race
+
+import (
+	"biathlon-prototype/events"
+	"biathlon-prototype/models"
+	"biathlon-prototype/utils"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// StartListEntry - строка стартового протокола
+type StartListEntry struct {
+	Order     int // Порядковый номер старта, с 1
+	AthleteID int
+	Entry     *models.Entry // Заявка из ростера, если он загружен
+	Start     time.Time     // Плановое время старта
+}
+
+// StartListProblem - несоответствие стартового протокола правилам старта
+type StartListProblem struct {
+	AthleteID int
+	Message   string
+}
+
+func (p StartListProblem) String() string {
+	return fmt.Sprintf("Участник(%d): %s", p.AthleteID, p.Message)
+}
+
+// RegisteredAthletes возвращает по возрастанию ID зарегистрированных участников
+func (r *Race) RegisteredAthletes() []int {
+	ids := make([]int, 0, len(r.Athletes))
+	for id, athlete := range r.Athletes {
+		if !athlete.RegisteredAt.IsZero() {
+			ids = append(ids, id)
+		}
+	}
+	sort.Ints(ids)
+	return ids
+}
+
+// DrawStartList проводит жеребьевку среди зарегистрированных участников:
+// порядок старта определяется генератором со стартовым значением seed,
+// поэтому одинаковый seed дает одинаковый протокол. Участники стартуют
+// с Config.Start через StartDelta, а при старте с гандикапом - все
+// в Config.Start. В форматах с общим стартом жеребьевка не проводится
+// (см. CheckLottery) и возвращается ошибка
+func (r *Race) DrawStartList(seed int64) ([]StartListEntry, error) {
+	if err := r.CheckLottery(); err != nil {
+		return nil, err
+	}
+
+	ids := r.RegisteredAthletes()
+	rand.New(rand.NewSource(seed)).Shuffle(len(ids), func(i, j int) {
+		ids[i], ids[j] = ids[j], ids[i]
+	})
+
+	list := make([]StartListEntry, len(ids))
+	for i, id := range ids {
+		start := r.StartTime
+		if r.Format.StartMode() == StartInterval {
+			start = start.Add(time.Duration(i) * r.StartDelta)
+		}
+		list[i] = StartListEntry{Order: i + 1, AthleteID: id, Entry: r.Athletes[id].Entry, Start: start}
+	}
+	return list, nil
+}
+
+// StartList строит стартовый протокол по назначенным жеребьевкой (событие 2)
+// временам старта зарегистрированных участников, по порядку старта.
+// Для формата с раздельным стартом проверяет, что каждое время совпадает
+// с Config.Start + n × StartDelta и занято одним участником. Участники без
+// времени старта в протокол не попадают и возвращаются как проблемы;
+// в эстафете в протокол входят только первые этапы
+func (r *Race) StartList() ([]StartListEntry, []StartListProblem) {
+	var list []StartListEntry
+	var problems []StartListProblem
+	for _, id := range r.RegisteredAthletes() {
+		athlete := r.Athletes[id]
+		start := r.Format.PlannedStart(r, athlete)
+		if start.IsZero() && relayLeg(athlete) > 1 {
+			continue // Следующие этапы эстафеты стартуют с передачи, а не по протоколу
+		}
+		if start.IsZero() {
+			problems = append(problems, StartListProblem{id, "время старта не назначено"})
+			continue
+		}
+		list = append(list, StartListEntry{AthleteID: id, Entry: athlete.Entry, Start: start})
+	}
+
+	sort.SliceStable(list, func(i, j int) bool {
+		return list[i].Start.Before(list[j].Start)
+	})
+	for i := range list {
+		list[i].Order = i + 1
+	}
+
+	if r.Format.StartMode() == StartInterval {
+		for i, entry := range list {
+			offset := entry.Start.Sub(r.StartTime)
+			if offset < 0 || offset%r.StartDelta != 0 {
+				problems = append(problems, StartListProblem{entry.AthleteID, fmt.Sprintf(
+					"время старта %s не совпадает с сеткой %s + n × %s",
+					utils.FormatTime(r.ReportTime(entry.Start)), utils.FormatTime(r.ReportTime(r.StartTime)),
+					utils.FormatDuration(r.StartDelta))})
+			}
+			if i > 0 && entry.Start.Equal(list[i-1].Start) {
+				problems = append(problems, StartListProblem{entry.AthleteID, fmt.Sprintf(
+					"время старта %s уже занято участником %d",
+					utils.FormatTime(r.ReportTime(entry.Start)), list[i-1].AthleteID)})
+			}
+		}
+	}
+	return list, problems
+}
+
+// LotteryEvents переводит стартовый протокол в события жеребьевки (2)
+// со временем at, чтобы передать их гонке как обычные события
+func LotteryEvents(list []StartListEntry, at time.Time) []events.Event {
+	lottery := make([]events.Event, len(list))
+	for i, entry := range list {
+		lottery[i] = events.Event{
+			Time:      at,
+			EventID:   events.EventStartTimeLottery,
+			AthleteID: entry.AthleteID,
+			Params:    []string{utils.FormatTime(entry.Start)},
+		}
+	}
+	return lottery
+}
+
+// WriteStartList выводит стартовый протокол текстом: строка на участника
+func (r *Race) WriteStartList(w io.Writer, list []StartListEntry) {
+	fmt.Fprintln(w, "Стартовый протокол:")
+	for _, entry := range list {
+		fmt.Fprintf(w, "%3d. %s  Участник %d", entry.Order, utils.FormatTime(r.ReportTime(entry.Start)), entry.AthleteID)
+		if entry.Entry != nil {
+			fmt.Fprintf(w, " (%s)", entry.Entry)
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+// StartListResults - стартовый протокол для экспорта в JSON
+type StartListResults struct {
+	Format     string          `json:"format"`
+	TimeZone   string          `json:"timeZone"`
+	Start      string          `json:"start"`
+	StartDelta string          `json:"startDelta"`
+	Athletes   []StartListItem `json:"athletes"`
+}
+
+// StartListItem - строка стартового протокола в JSON
+type StartListItem struct {
+	Order     int           `json:"order"`
+	AthleteID int           `json:"athleteId"`
+	Entry     *models.Entry `json:"entry,omitempty"`
+	Start     string        `json:"start"`
+}
+
+// WriteStartListJSON выводит стартовый протокол в JSON
+func (r *Race) WriteStartListJSON(w io.Writer, list []StartListEntry) error {
+	results := StartListResults{
+		Format:     r.Format.Name(),
+		TimeZone:   r.ReportZone.String(),
+		Start:      utils.FormatTime(r.ReportTime(r.StartTime)),
+		StartDelta: utils.FormatDuration(r.StartDelta),
+		Athletes:   make([]StartListItem, len(list)),
+	}
+	for i, entry := range list {
+		results.Athletes[i] = StartListItem{
+			Order:     entry.Order,
+			AthleteID: entry.AthleteID,
+			Entry:     entry.Entry,
+			Start:     utils.FormatTime(r.ReportTime(entry.Start)),
+		}
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(results)
+}
+
+// WriteStartListCSV выводит стартовый протокол в CSV
+func (r *Race) WriteStartListCSV(w io.Writer, list []StartListEntry) error {
+	writer := csv.NewWriter(w)
+	header := []string{"order", "start", "athlete_id", "bib", "name", "nation", "team", "category"}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	for _, entry := range list {
+		row := []string{
+			strconv.Itoa(entry.Order),
+			utils.FormatTime(r.ReportTime(entry.Start)),
+			strconv.Itoa(entry.AthleteID),
+		}
+		row = append(row, entryColumns(entry.Entry)...)
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
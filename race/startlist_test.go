@@ -0,0 +1,168 @@
+package race
+
+import (
+	"biathlon-prototype/events"
+	"biathlon-prototype/utils"
+	"bytes"
+	"encoding/csv"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDrawStartList(t *testing.T) {
+	r := createTestRace()
+	r.Output = io.Discard
+	for id := 1; id <= 5; id++ {
+		registerAthlete(r, id)
+	}
+
+	list, err := r.DrawStartList(42)
+	if err != nil {
+		t.Fatalf("DrawStartList() error = %v", err)
+	}
+	if len(list) != 5 {
+		t.Fatalf("DrawStartList() returned %d entries, want 5", len(list))
+	}
+	seen := make(map[int]bool)
+	for i, entry := range list {
+		want := r.StartTime.Add(time.Duration(i) * r.StartDelta)
+		if entry.Order != i+1 || !entry.Start.Equal(want) {
+			t.Errorf("list[%d] = order %d at %s, want order %d at %s",
+				i, entry.Order, utils.FormatTime(entry.Start), i+1, utils.FormatTime(want))
+		}
+		seen[entry.AthleteID] = true
+	}
+	if len(seen) != 5 {
+		t.Errorf("Drawn athletes = %v, want each of 5 once", seen)
+	}
+
+	// Одинаковый seed - одинаковый протокол
+	again, _ := r.DrawStartList(42)
+	for i := range list {
+		if again[i].AthleteID != list[i].AthleteID {
+			t.Fatalf("DrawStartList(42) is not reproducible: %v vs %v", again, list)
+		}
+	}
+
+	// Жеребьевку можно передать гонке событиями 2, и протокол по ним совпадет
+	for _, event := range LotteryEvents(list, createTestEvent(0, "09:30:00.000", 0).Time) {
+		if err := r.HandleEvent(event); err != nil {
+			t.Fatalf("HandleEvent(%s) error = %v", event, err)
+		}
+	}
+	fromLottery, problems := r.StartList()
+	if len(problems) != 0 {
+		t.Errorf("StartList() problems = %v, want none", problems)
+	}
+	for i := range list {
+		if fromLottery[i].AthleteID != list[i].AthleteID {
+			t.Errorf("StartList()[%d] = athlete %d, want %d", i, fromLottery[i].AthleteID, list[i].AthleteID)
+		}
+	}
+}
+
+func TestStartList_Problems(t *testing.T) {
+	r := createTestRace()
+	r.Output = io.Discard
+	for id := 1; id <= 4; id++ {
+		registerAthlete(r, id)
+	}
+	for _, e := range []events.Event{
+		createTestEvent(events.EventStartTimeLottery, "09:10:00.000", 1, "10:00:00.000"),
+		createTestEvent(events.EventStartTimeLottery, "09:10:00.000", 2, "10:01:00.000"),
+		createTestEvent(events.EventStartTimeLottery, "09:10:00.000", 3, "10:01:00.000"),
+		createTestEvent(events.EventStartTimeLottery, "09:10:00.000", 4, "10:02:30.000"),
+	} {
+		r.HandleEvent(e)
+	}
+	registerAthlete(r, 5) // Без жеребьевки
+
+	list, problems := r.StartList()
+	if len(list) != 4 {
+		t.Errorf("StartList() has %d entries, want 4", len(list))
+	}
+
+	want := []struct {
+		athleteID int
+		message   string
+	}{
+		{5, "не назначено"},
+		{3, "уже занято участником 2"},
+		{4, "не совпадает с сеткой"},
+	}
+	if len(problems) != len(want) {
+		t.Fatalf("StartList() problems = %v, want %d", problems, len(want))
+	}
+	for i, w := range want {
+		if problems[i].AthleteID != w.athleteID || !strings.Contains(problems[i].Message, w.message) {
+			t.Errorf("problems[%d] = %v, want athlete %d: %q", i, problems[i], w.athleteID, w.message)
+		}
+	}
+}
+
+func TestStartList_MassStartIsCommon(t *testing.T) {
+	cfg := createTestRace().Config
+	cfg.Format = FormatMassStart
+	r, _ := NewRace(cfg)
+	r.Output = io.Discard
+	registerAthlete(r, 1)
+	registerAthlete(r, 2)
+
+	list, problems := r.StartList()
+	if len(problems) != 0 || len(list) != 2 {
+		t.Fatalf("StartList() = %v, %v, want 2 entries without problems", list, problems)
+	}
+	for _, entry := range list {
+		if !entry.Start.Equal(r.StartTime) {
+			t.Errorf("Athlete %d starts at %s, want common start", entry.AthleteID, utils.FormatTime(entry.Start))
+		}
+	}
+}
+
+func TestDrawStartList_CommonStartRefused(t *testing.T) {
+	for _, format := range []string{FormatMassStart, FormatRelay} {
+		t.Run(format, func(t *testing.T) {
+			cfg := createTestRace().Config
+			cfg.Format = format
+			r, _ := NewRace(cfg)
+			r.Output = io.Discard
+			registerAthlete(r, 1)
+
+			if err := r.CheckLottery(); err == nil {
+				t.Error("CheckLottery() expected error for common start, got nil")
+			}
+			if list, err := r.DrawStartList(42); err == nil {
+				t.Errorf("DrawStartList() = %v, want error for common start", list)
+			}
+		})
+	}
+
+	// При раздельном старте жеребьевка допустима
+	if err := createTestRace().CheckLottery(); err != nil {
+		t.Errorf("CheckLottery() for interval start error = %v", err)
+	}
+}
+
+func TestWriteStartListCSV(t *testing.T) {
+	r := createTestRace()
+	r.Output = io.Discard
+	registerAthlete(r, 7)
+
+	list, err := r.DrawStartList(1)
+	if err != nil {
+		t.Fatalf("DrawStartList() error = %v", err)
+	}
+	var buf bytes.Buffer
+	if err := r.WriteStartListCSV(&buf, list); err != nil {
+		t.Fatalf("WriteStartListCSV() error = %v", err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("Failed to read CSV: %v", err)
+	}
+	if len(records) != 2 || records[1][0] != "1" || records[1][1] != "10:00:00.000" || records[1][2] != "7" {
+		t.Errorf("CSV records = %v", records)
+	}
+}
@@ -0,0 +1,384 @@
+package race
+
+import (
+	"biathlon-prototype/events"
+	"biathlon-prototype/models"
+	"biathlon-prototype/utils"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// ViolationKind - причина, по которой событие не было применено
+type ViolationKind string
+
+const (
+	ViolationOrder        ViolationKind = "order"         // Событие недопустимо в текущем состоянии участника
+	ViolationUnknownEvent ViolationKind = "unknown-event" // Неизвестный ID события
+	ViolationBadParams    ViolationKind = "bad-params"    // Нет обязательного параметра или он некорректен
+)
+
+// violationKinds - порядок вывода причин в сводке
+var violationKinds = []ViolationKind{ViolationOrder, ViolationUnknownEvent, ViolationBadParams}
+
+// Violation - событие, которое нельзя применить к гонке.
+// Такие события не меняют гонку, а только попадают в Race.Violations
+type Violation struct {
+	Time      time.Time
+	AthleteID int
+	EventID   int
+	Kind      ViolationKind
+	State     models.State // Состояние участника в момент события
+	Reason    string
+	Raw       string // Исходная строка события, если есть
+}
+
+func (v Violation) String() string {
+	state := v.State
+	if state == models.StateNone {
+		state = "не зарегистрирован"
+	}
+	return fmt.Sprintf("[%s] Участник(%d): событие %d отклонено (состояние %s): %s",
+		utils.FormatTime(v.Time), v.AthleteID, v.EventID, state, v.Reason)
+}
+
+func (v Violation) Error() string {
+	return v.String()
+}
+
+// transition описывает допустимый переход: из каких состояний
+// можно получить событие и в какое состояние оно переводит участника.
+// Пустое next означает, что состояние не меняется
+type transition struct {
+	from []models.State
+	next models.State
+}
+
+// beforeFinishStates - все состояния от регистрации до финиша
+var beforeFinishStates = []models.State{
+	models.StateRegistered, models.StateScheduled, models.StateAtStartLine,
+	models.StateRacing, models.StateAtFiringLine, models.StatePenalty,
+}
+
+// afterRegisterStates - состояния, в которых участника еще можно
+// дисквалифицировать, в том числе после финиша
+var afterRegisterStates = []models.State{
+	models.StateRegistered, models.StateScheduled, models.StateAtStartLine,
+	models.StateRacing, models.StateAtFiringLine, models.StatePenalty, models.StateFinished,
+}
+
+var transitions = map[int]transition{
+	events.EventRegister:         {from: []models.State{models.StateNone}, next: models.StateRegistered},
+	events.EventStartTimeLottery: {from: []models.State{models.StateRegistered, models.StateScheduled}, next: models.StateScheduled},
+	events.EventAtStartLine:      {from: []models.State{models.StateScheduled}, next: models.StateAtStartLine},
+	events.EventStart:            {from: []models.State{models.StateAtStartLine}, next: models.StateRacing},
+	events.EventAtFiringLine:     {from: []models.State{models.StateRacing}, next: models.StateAtFiringLine},
+	events.EventHitSuccessful:    {from: []models.State{models.StateAtFiringLine}},
+	events.EventHitMissed:        {from: []models.State{models.StateAtFiringLine}},
+	events.EventLeaveFiringLine:  {from: []models.State{models.StateAtFiringLine}, next: models.StateRacing},
+	events.EventEnterPenalty:     {from: []models.State{models.StateRacing}, next: models.StatePenalty},
+	events.EventLeavePenalty:     {from: []models.State{models.StatePenalty}, next: models.StateRacing},
+	events.EventLapFinish:        {from: []models.State{models.StateRacing}},
+	events.EventCantContinue:     {from: beforeFinishStates, next: models.StateOut},
+	events.EventExchange:         {from: []models.State{models.StateRacing}, next: models.StateFinished},
+	events.EventSpareRound:       {from: []models.State{models.StateAtFiringLine}},
+	events.EventDisqualified:     {from: afterRegisterStates, next: models.StateOut},
+	events.EventFinished:         {from: []models.State{models.StateRacing}, next: models.StateFinished},
+}
+
+// nextState проверяет, может ли участник получить событие, и возвращает
+// состояние после него. Для незарегистрированного участника athlete == nil
+func (r *Race) nextState(athlete *models.Athlete, event events.Event) (models.State, error) {
+	state := models.StateNone
+	if athlete != nil {
+		state = athlete.State
+	}
+
+	// Неизвестные события отсекает CheckParams
+	t := transitions[event.EventID]
+
+	if !containsState(t.from, state) {
+		return state, fmt.Errorf("ожидалось состояние %s", joinStates(t.from))
+	}
+
+	// Дополнительные условия, которые не выражаются одним состоянием
+	switch event.EventID {
+	case events.EventLapFinish:
+		if athlete.CurrentLap >= r.Config.Laps {
+			return state, fmt.Errorf("все %d круга(ов) уже пройдены", r.Config.Laps)
+		}
+	case events.EventFinished, events.EventExchange:
+		if athlete.CurrentLap < r.Config.Laps {
+			return state, fmt.Errorf("пройдено %d из %d кругов", athlete.CurrentLap, r.Config.Laps)
+		}
+	}
+
+	if t.next == models.StateNone {
+		return state, nil
+	}
+	return t.next, nil
+}
+
+// reject записывает событие, которое не удалось применить, и возвращает запись о нем
+func (r *Race) reject(athlete *models.Athlete, event events.Event, kind ViolationKind, reason error) Violation {
+	state := models.StateNone
+	if athlete != nil {
+		state = athlete.State
+	}
+	violation := Violation{
+		Time:      event.Time,
+		AthleteID: event.AthleteID,
+		EventID:   event.EventID,
+		Kind:      kind,
+		State:     state,
+		Reason:    reason.Error(),
+		Raw:       event.Raw,
+	}
+	r.Violations = append(r.Violations, violation)
+	return violation
+}
+
+// ParamRule описывает параметры события одного вида. Таблица правил общая
+// для обработки гонки и проверки файла событий
+type ParamRule struct {
+	Required int    // Сколько параметров обязательно
+	Optional int    // Сколько необязательных параметров может идти следом
+	Extra    bool   // Допустимы ли дополнительные параметры
+	Lenient  bool   // Без обязательного параметра событие все равно применяется
+	Missing  string // Что за обязательный параметр, для сообщения о его отсутствии
+
+	// check проверяет значения параметров, когда обязательные есть
+	check func(params []string) error
+	// limit проверяет уже корректные значения по конфигурации гонки
+	limit func(r *Race, params []string) error
+}
+
+var paramRules = map[int]ParamRule{
+	events.EventRegister:         {},
+	events.EventStartTimeLottery: {Required: 1, Missing: "время старта", check: checkStartTime},
+	events.EventAtStartLine:      {},
+	events.EventStart:            {},
+	events.EventAtFiringLine:     {Required: 1, Optional: 1, Missing: "номер огневого рубежа", check: checkFiringLine, limit: (*Race).limitFiringLine},
+	events.EventHitSuccessful:    {Required: 1, Missing: "номер мишени", check: checkTarget},
+	events.EventLeaveFiringLine:  {},
+	events.EventEnterPenalty:     {},
+	events.EventLeavePenalty:     {},
+	events.EventLapFinish:        {},
+	// Причина схода - текст с пробелами, без нее подставляется причина по умолчанию
+	events.EventCantContinue: {Required: 1, Extra: true, Lenient: true, Missing: "причина схода"},
+	events.EventExchange:     {Required: 1, Missing: "участник, принимающий эстафету", check: checkExchange},
+	events.EventSpareRound:   {},
+	events.EventDisqualified: {},
+	events.EventFinished:     {Optional: 1, check: checkPhotoFinish},
+	events.EventHitMissed:    {Required: 1, Missing: "номер мишени", check: checkTarget},
+}
+
+// Params возвращает правило для параметров события eventID; false - событие неизвестно
+func Params(eventID int) (ParamRule, bool) {
+	rule, known := paramRules[eventID]
+	return rule, known
+}
+
+// ParamError - отсутствующий или некорректный параметр события
+type ParamError struct {
+	Index  int // Номер параметра с 0; равен числу параметров, если обязательного нет
+	Reason string
+}
+
+func (e ParamError) Error() string {
+	return e.Reason
+}
+
+// CheckParams проверяет, что событие известно и у него есть корректные
+// обязательные параметры, в том числе в пределах конфигурации гонки.
+// Ошибка в параметре возвращается как ParamError
+func (r *Race) CheckParams(event events.Event) (ViolationKind, error) {
+	rule, known := paramRules[event.EventID]
+	if !known {
+		return ViolationUnknownEvent, fmt.Errorf("неизвестное событие %d", event.EventID)
+	}
+	if len(event.Params) < rule.Required {
+		if rule.Lenient {
+			return "", nil
+		}
+		return ViolationBadParams, ParamError{
+			Index:  len(event.Params),
+			Reason: fmt.Sprintf("нет обязательного параметра: %s", rule.Missing),
+		}
+	}
+	if rule.check != nil {
+		if err := rule.check(event.Params); err != nil {
+			return ViolationBadParams, err
+		}
+	}
+	if rule.limit != nil {
+		if err := rule.limit(r, event.Params); err != nil {
+			return ViolationBadParams, err
+		}
+	}
+	return "", nil
+}
+
+// positiveParam проверяет, что i-й параметр - положительное число.
+// format - сообщение об ошибке с местом для значения параметра
+func positiveParam(params []string, i int, format string) error {
+	if n, err := strconv.Atoi(params[i]); err != nil || n < 1 {
+		return ParamError{Index: i, Reason: fmt.Sprintf(format, params[i])}
+	}
+	return nil
+}
+
+func checkStartTime(params []string) error {
+	if _, err := utils.ParseTime(params[0]); err != nil {
+		return ParamError{Index: 0, Reason: fmt.Sprintf("некорректное время старта %q, ожидается ЧЧ:ММ:СС.ммм", params[0])}
+	}
+	return nil
+}
+
+func checkFiringLine(params []string) error {
+	if err := positiveParam(params, 0, "номер огневого рубежа должен быть положительным числом: %q"); err != nil {
+		return err
+	}
+	// Второй необязательный параметр - номер стрелковой позиции
+	if len(params) > 1 {
+		return positiveParam(params, 1, "номер стрелковой позиции должен быть положительным числом: %q")
+	}
+	return nil
+}
+
+// limitFiringLine проверяет, что огневой рубеж есть в конфигурации
+func (r *Race) limitFiringLine(params []string) error {
+	firingLine, _ := strconv.Atoi(params[0])
+	if lines := r.Config.FiringLines; lines > 0 && firingLine > lines {
+		return ParamError{Index: 0, Reason: fmt.Sprintf("огневой рубеж %d вне диапазона 1..%d из конфигурации", firingLine, lines)}
+	}
+	return nil
+}
+
+func checkTarget(params []string) error {
+	target, err := strconv.Atoi(params[0])
+	if err != nil {
+		return ParamError{Index: 0, Reason: fmt.Sprintf("номер мишени должен быть числом: %q", params[0])}
+	}
+	if target < 1 || target > models.TargetsPerStage {
+		return ParamError{Index: 0, Reason: fmt.Sprintf("мишень %d вне диапазона 1..%d", target, models.TargetsPerStage)}
+	}
+	return nil
+}
+
+func checkExchange(params []string) error {
+	return positiveParam(params, 0, "ID принимающего участника должен быть положительным числом: %q")
+}
+
+// checkPhotoFinish проверяет необязательное место по фотофинишу
+// среди финишировавших одновременно
+func checkPhotoFinish(params []string) error {
+	if len(params) > 0 {
+		return positiveParam(params, 0, "место по фотофинишу должно быть положительным числом: %q")
+	}
+	return nil
+}
+
+// beforeStart сообщает, что участник в состоянии state еще не стартовал
+func beforeStart(state models.State) bool {
+	switch state {
+	case models.StateRegistered, models.StateScheduled, models.StateAtStartLine:
+		return true
+	}
+	return false
+}
+
+// checkStartMode отклоняет жеребьевку там, где время старта общее для всех
+func (r *Race) checkStartMode(event events.Event) error {
+	if event.EventID == events.EventStartTimeLottery {
+		return r.CheckLottery()
+	}
+	return nil
+}
+
+// CheckLottery возвращает ошибку, если в формате гонки жеребьевка времени
+// старта (событие 2) не проводится: при общем старте все стартуют вместе
+func (r *Race) CheckLottery() error {
+	if r.Format.StartMode() == StartCommon {
+		return fmt.Errorf("в формате %s все стартуют вместе в %s, жеребьевка не проводится",
+			r.Format.Name(), utils.FormatTime(r.ReportTime(r.StartTime)))
+	}
+	return nil
+}
+
+// checkShot проверяет выстрел по текущей стрельбе участника: закрытую
+// мишень нельзя закрыть повторно, а вне эстафеты патронов на стрельбе
+// столько же, сколько мишеней (в эстафете патроны считает checkRelay).
+// Вызывается после проверки состояния, поэтому стрельба уже начата
+func (r *Race) checkShot(athlete *models.Athlete, event events.Event) error {
+	if event.EventID != events.EventHitSuccessful && event.EventID != events.EventHitMissed {
+		return nil
+	}
+	stage := athlete.CurrentStage()
+	if stage == nil {
+		return nil
+	}
+	if r.Format.Name() != FormatRelay && stage.Shots >= models.TargetsPerStage {
+		return fmt.Errorf("на стрельбе %d уже сделаны все %d выстрелов", stage.Stage, models.TargetsPerStage)
+	}
+	target, _ := strconv.Atoi(event.Params[0])
+	if event.EventID == events.EventHitSuccessful && stage.TargetClosed(target) {
+		return fmt.Errorf("мишень %d уже закрыта на рубеже %d", target, stage.Stage)
+	}
+	return nil
+}
+
+// ViolationSummary возвращает количество непримененных событий по причинам
+func (r *Race) ViolationSummary() map[ViolationKind]int {
+	summary := make(map[ViolationKind]int)
+	for _, v := range r.Violations {
+		summary[v.Kind]++
+	}
+	return summary
+}
+
+// ViolationSummaryText описывает сводку непримененных событий одной строкой
+func (r *Race) ViolationSummaryText() string {
+	summary := r.ViolationSummary()
+	names := map[ViolationKind]string{
+		ViolationOrder:        "нарушение порядка",
+		ViolationUnknownEvent: "неизвестное событие",
+		ViolationBadParams:    "некорректные параметры",
+	}
+
+	text := fmt.Sprintf("Не применено событий: %d", len(r.Violations))
+	separator := " ("
+	for _, kind := range violationKinds {
+		if summary[kind] > 0 {
+			text += fmt.Sprintf("%s%s: %d", separator, names[kind], summary[kind])
+			separator = ", "
+		}
+	}
+	if separator == ", " {
+		text += ")"
+	}
+	return text
+}
+
+func containsState(states []models.State, state models.State) bool {
+	for _, s := range states {
+		if s == state {
+			return true
+		}
+	}
+	return false
+}
+
+func joinStates(states []models.State) string {
+	result := ""
+	for i, s := range states {
+		if i > 0 {
+			result += " или "
+		}
+		if s == models.StateNone {
+			s = "не зарегистрирован"
+		}
+		result += string(s)
+	}
+	return result
+}
@@ -0,0 +1,364 @@
+package race
+
+import (
+	"biathlon-prototype/events"
+	"biathlon-prototype/models"
+	"io"
+	"testing"
+)
+
+func TestStateMachine_LegalSequence(t *testing.T) {
+	r := createTestRace()
+	registerAndStartAthlete(r, 1)
+	r.HandleEvent(createTestEvent(events.EventAtFiringLine, "10:10:00.000", 1, "1"))
+	r.HandleEvent(createTestEvent(events.EventHitMissed, "10:10:05.000", 1, "1"))
+	r.HandleEvent(createTestEvent(events.EventLeaveFiringLine, "10:10:30.000", 1))
+	r.HandleEvent(createTestEvent(events.EventEnterPenalty, "10:10:35.000", 1))
+	r.HandleEvent(createTestEvent(events.EventLeavePenalty, "10:11:05.000", 1))
+	finishAthlete(r, 1)
+
+	if len(r.Violations) != 0 {
+		t.Fatalf("Expected no violations, got %v", r.Violations)
+	}
+
+	if state := r.Athletes[1].State; state != models.StateFinished {
+		t.Errorf("Expected state Finished, got %v", state)
+	}
+}
+
+func TestStateMachine_Violations(t *testing.T) {
+	testCases := []struct {
+		name      string
+		prepare   func(r *Race)
+		event     events.Event
+		wantState models.State
+	}{
+		{
+			name:      "Event before registration",
+			prepare:   func(r *Race) {},
+			event:     createTestEvent(events.EventStart, "10:00:00.000", 1),
+			wantState: models.StateNone,
+		},
+		{
+			name:      "Firing before start",
+			prepare:   func(r *Race) { registerAthlete(r, 1) },
+			event:     createTestEvent(events.EventAtFiringLine, "10:10:00.000", 1, "1"),
+			wantState: models.StateRegistered,
+		},
+		{
+			name:      "Shot outside firing line",
+			prepare:   func(r *Race) { registerAndStartAthlete(r, 1) },
+			event:     createTestEvent(events.EventHitSuccessful, "10:10:00.000", 1, "1"),
+			wantState: models.StateRacing,
+		},
+		{
+			name:      "Leave penalty never entered",
+			prepare:   func(r *Race) { registerAndStartAthlete(r, 1) },
+			event:     createTestEvent(events.EventLeavePenalty, "10:10:00.000", 1),
+			wantState: models.StateRacing,
+		},
+		{
+			name: "Finish before all laps",
+			prepare: func(r *Race) {
+				registerAndStartAthlete(r, 1)
+				r.HandleEvent(createTestEvent(events.EventLapFinish, "10:30:00.000", 1))
+			},
+			event:     createTestEvent(events.EventFinished, "10:31:00.000", 1),
+			wantState: models.StateRacing,
+		},
+		{
+			name: "Lap after finish",
+			prepare: func(r *Race) {
+				registerAndStartAthlete(r, 1)
+				finishAthlete(r, 1)
+			},
+			event:     createTestEvent(events.EventLapFinish, "12:00:00.000", 1),
+			wantState: models.StateFinished,
+		},
+		{
+			name:      "Double registration",
+			prepare:   func(r *Race) { registerAthlete(r, 1) },
+			event:     createTestEvent(events.EventRegister, "09:01:00.000", 1),
+			wantState: models.StateRegistered,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := createTestRace()
+			tc.prepare(r)
+			if len(r.Violations) != 0 {
+				t.Fatalf("Unexpected violations while preparing: %v", r.Violations)
+			}
+
+			r.HandleEvent(tc.event)
+
+			if len(r.Violations) != 1 {
+				t.Fatalf("Expected 1 violation, got %d", len(r.Violations))
+			}
+
+			v := r.Violations[0]
+			if v.EventID != tc.event.EventID || v.AthleteID != tc.event.AthleteID {
+				t.Errorf("Violation = %+v, want event %d for athlete %d", v, tc.event.EventID, tc.event.AthleteID)
+			}
+			if v.State != tc.wantState {
+				t.Errorf("Violation state = %v, want %v", v.State, tc.wantState)
+			}
+			if v.Reason == "" {
+				t.Error("Expected violation reason to be set")
+			}
+		})
+	}
+}
+
+func TestStateMachine_RejectedEventDoesNotChangeRace(t *testing.T) {
+	r := createTestRace()
+	r.HandleEvent(createTestEvent(events.EventStart, "10:00:00.000", 1))
+
+	if len(r.Athletes) != 0 {
+		t.Errorf("Expected no athletes after rejected event, got %d", len(r.Athletes))
+	}
+}
+
+func TestStateMachine_DisqualifyAfterFinish(t *testing.T) {
+	r := createTestRace()
+	registerAndStartAthlete(r, 1)
+	finishAthlete(r, 1)
+
+	r.HandleEvent(createTestEvent(events.EventDisqualified, "12:00:00.000", 1))
+
+	athlete := r.Athletes[1]
+	if athlete.Status != models.StatusDisqualified {
+		t.Errorf("Expected status Disqualified, got %v", athlete.Status)
+	}
+	if athlete.State != models.StateOut {
+		t.Errorf("Expected state Out, got %v", athlete.State)
+	}
+}
+
+func TestHandleEvent_ReturnsViolation(t *testing.T) {
+	testCases := []struct {
+		name     string
+		event    events.Event
+		wantKind ViolationKind
+	}{
+		{
+			name:     "Unknown event",
+			event:    createTestEvent(14, "10:10:00.000", 1),
+			wantKind: ViolationUnknownEvent,
+		},
+		{
+			name:     "Lottery without start time",
+			event:    createTestEvent(events.EventStartTimeLottery, "10:10:00.000", 1),
+			wantKind: ViolationBadParams,
+		},
+		{
+			name:     "Lottery with bad start time",
+			event:    createTestEvent(events.EventStartTimeLottery, "10:10:00.000", 1, "soon"),
+			wantKind: ViolationBadParams,
+		},
+		{
+			name:     "Non-numeric firing line",
+			event:    createTestEvent(events.EventAtFiringLine, "10:10:00.000", 1, "first"),
+			wantKind: ViolationBadParams,
+		},
+		{
+			name:     "Miss without target",
+			event:    createTestEvent(events.EventHitMissed, "10:10:00.000", 1),
+			wantKind: ViolationBadParams,
+		},
+		{
+			name:     "Target out of range",
+			event:    createTestEvent(events.EventHitSuccessful, "10:10:00.000", 1, "6"),
+			wantKind: ViolationBadParams,
+		},
+		{
+			name:     "Non-numeric target",
+			event:    createTestEvent(events.EventHitMissed, "10:10:00.000", 1, "first"),
+			wantKind: ViolationBadParams,
+		},
+		{
+			name:     "Out of order",
+			event:    createTestEvent(events.EventFinished, "10:10:00.000", 1),
+			wantKind: ViolationOrder,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := createTestRace()
+			registerAthlete(r, 1)
+
+			err := r.HandleEvent(tc.event)
+			if err == nil {
+				t.Fatal("HandleEvent() expected error, got nil")
+			}
+
+			v, ok := err.(Violation)
+			if !ok {
+				t.Fatalf("HandleEvent() error type = %T, want Violation", err)
+			}
+			if v.Kind != tc.wantKind {
+				t.Errorf("Violation kind = %s, want %s", v.Kind, tc.wantKind)
+			}
+			if len(r.Violations) != 1 {
+				t.Errorf("Expected 1 recorded violation, got %d", len(r.Violations))
+			}
+		})
+	}
+}
+
+func TestHandleEvent_NoErrorForValidEvent(t *testing.T) {
+	r := createTestRace()
+	if err := r.HandleEvent(createTestEvent(events.EventRegister, "09:00:00.000", 1)); err != nil {
+		t.Errorf("HandleEvent() unexpected error = %v", err)
+	}
+}
+
+func TestViolationSummary(t *testing.T) {
+	r := createTestRace()
+	registerAthlete(r, 1)
+	r.HandleEvent(createTestEvent(14, "10:00:00.000", 1))
+	r.HandleEvent(createTestEvent(15, "10:00:01.000", 1))
+	r.HandleEvent(createTestEvent(events.EventAtFiringLine, "10:00:02.000", 1, "x"))
+	r.HandleEvent(createTestEvent(events.EventFinished, "10:00:03.000", 1))
+
+	summary := r.ViolationSummary()
+	if summary[ViolationUnknownEvent] != 2 || summary[ViolationBadParams] != 1 || summary[ViolationOrder] != 1 {
+		t.Errorf("ViolationSummary() = %v, want 2 unknown, 1 bad params, 1 order", summary)
+	}
+
+	want := "Не применено событий: 4 (нарушение порядка: 1, неизвестное событие: 2, некорректные параметры: 1)"
+	if got := r.ViolationSummaryText(); got != want {
+		t.Errorf("ViolationSummaryText() = %q, want %q", got, want)
+	}
+}
+
+func TestHandleEvent_DuplicateHit(t *testing.T) {
+	r := createTestRace()
+	r.Output = io.Discard
+	registerAndStartAthlete(r, 1)
+
+	for _, e := range []events.Event{
+		createTestEvent(events.EventAtFiringLine, "10:10:00.000", 1, "1"),
+		createTestEvent(events.EventHitSuccessful, "10:10:05.000", 1, "1"),
+		createTestEvent(events.EventHitMissed, "10:10:10.000", 1, "2"),
+	} {
+		if err := r.HandleEvent(e); err != nil {
+			t.Fatalf("HandleEvent() unexpected error = %v", err)
+		}
+	}
+
+	// Закрытую мишень нельзя закрыть повторно
+	err := r.HandleEvent(createTestEvent(events.EventHitSuccessful, "10:10:15.000", 1, "1"))
+	if v, ok := err.(Violation); !ok || v.Kind != ViolationBadParams {
+		t.Fatalf("HandleEvent() error = %v, want bad-params violation", err)
+	}
+	// Промах по открытой мишени можно исправить попаданием, а промах по закрытой ее не открывает
+	for _, e := range []events.Event{
+		createTestEvent(events.EventHitSuccessful, "10:10:20.000", 1, "2"),
+		createTestEvent(events.EventHitMissed, "10:10:25.000", 1, "1"),
+	} {
+		if err := r.HandleEvent(e); err != nil {
+			t.Fatalf("HandleEvent() unexpected error = %v", err)
+		}
+	}
+
+	stage := r.Athletes[1].CurrentStage()
+	if stage.Shots != 4 || stage.Hits != 2 {
+		t.Errorf("Stage shots/hits = %d/%d, want 4/2", stage.Shots, stage.Hits)
+	}
+	if card := stage.TargetCard(); card != "●●---" {
+		t.Errorf("TargetCard() = %q, want \"●●---\"", card)
+	}
+}
+
+func TestHandleEvent_ShotLimit(t *testing.T) {
+	r := createTestRace()
+	r.Output = io.Discard
+	registerAndStartAthlete(r, 1)
+
+	if err := r.HandleEvent(createTestEvent(events.EventAtFiringLine, "10:10:00.000", 1, "1")); err != nil {
+		t.Fatalf("HandleEvent() unexpected error = %v", err)
+	}
+	for i := 0; i < models.TargetsPerStage; i++ {
+		if err := r.HandleEvent(createTestEvent(events.EventHitMissed, "10:10:10.000", 1, "1")); err != nil {
+			t.Fatalf("HandleEvent() shot %d unexpected error = %v", i+1, err)
+		}
+	}
+
+	// Шестой выстрел вне эстафеты сделать нечем
+	err := r.HandleEvent(createTestEvent(events.EventHitMissed, "10:10:20.000", 1, "1"))
+	if v, ok := err.(Violation); !ok || v.Kind != ViolationBadParams {
+		t.Fatalf("HandleEvent() error = %v, want bad-params violation", err)
+	}
+	stage := r.Athletes[1].CurrentStage()
+	if stage.Shots != models.TargetsPerStage || stage.LoopsOwed != models.TargetsPerStage {
+		t.Errorf("Stage shots/loops = %d/%d, want %d/%d",
+			stage.Shots, stage.LoopsOwed, models.TargetsPerStage, models.TargetsPerStage)
+	}
+}
+
+func TestCheckParams_ParamIndex(t *testing.T) {
+	testCases := []struct {
+		name      string
+		event     events.Event
+		wantIndex int
+	}{
+		{
+			name:      "Missing start time",
+			event:     createTestEvent(events.EventStartTimeLottery, "10:10:00.000", 1),
+			wantIndex: 0,
+		},
+		{
+			name:      "Bad lane",
+			event:     createTestEvent(events.EventAtFiringLine, "10:10:00.000", 1, "1", "x"),
+			wantIndex: 1,
+		},
+		{
+			name:      "Bad photo finish place",
+			event:     createTestEvent(events.EventFinished, "10:10:00.000", 1, "0"),
+			wantIndex: 0,
+		},
+		{
+			name:      "Firing line beyond config",
+			event:     createTestEvent(events.EventAtFiringLine, "10:10:00.000", 1, "3"),
+			wantIndex: 0,
+		},
+	}
+	r := createTestRace()
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			kind, err := r.CheckParams(tc.event)
+			if kind != ViolationBadParams {
+				t.Errorf("CheckParams() kind = %q, want %q", kind, ViolationBadParams)
+			}
+			perr, ok := err.(ParamError)
+			if !ok {
+				t.Fatalf("CheckParams() error type = %T, want ParamError", err)
+			}
+			if perr.Index != tc.wantIndex {
+				t.Errorf("ParamError.Index = %d, want %d", perr.Index, tc.wantIndex)
+			}
+		})
+	}
+
+	// Без причины схода событие применяется, причина подставляется
+	if _, err := r.CheckParams(createTestEvent(events.EventCantContinue, "10:10:00.000", 1)); err != nil {
+		t.Errorf("CheckParams() for event without reason = %v, want nil", err)
+	}
+}
+
+func TestParamRulesCoverTransitions(t *testing.T) {
+	for id := range transitions {
+		if _, known := Params(id); !known {
+			t.Errorf("No param rule for event %d", id)
+		}
+	}
+	for id := range paramRules {
+		if _, known := transitions[id]; !known {
+			t.Errorf("Param rule for event %d without transition", id)
+		}
+	}
+}
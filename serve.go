@@ -0,0 +1,76 @@
+package main
+
+import (
+	"biathlon-prototype/server"
+	"context"
+	"errors"
+	"flag"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+)
+
+// Как часто проверять файл событий на новые строки
+const tailPollInterval = 500 * time.Millisecond
+
+func runServe(args []string) int {
+	var addr, tailPath string
+	opts, err := parseOptions("serve", args, func(fs *flag.FlagSet) {
+		fs.StringVar(&addr, "addr", ":8080", "адрес HTTP-сервера")
+		fs.StringVar(&tailPath, "tail", "", "файл событий, за которым следить (- для stdin); без него события принимаются только через POST /api/events")
+	})
+	if err != nil {
+		return exitCode(err)
+	}
+
+	r, err := loadRace(opts)
+	if err != nil {
+		log.Print(err)
+		return 1
+	}
+
+	srv := server.New(r)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if tailPath != "" {
+		onError := func(line string, err error) {
+			log.Printf("Событие не применено: %v (содержимое: %q)", err, line)
+		}
+		go func() {
+			var err error
+			if tailPath == "-" {
+				err = srv.Follow(ctx, os.Stdin, tailPollInterval, onError)
+			} else {
+				err = srv.Tail(ctx, tailPath, tailPollInterval, onError)
+			}
+			if err != nil {
+				log.Printf("Ошибка чтения событий: %v", err)
+			}
+		}()
+	}
+
+	// Запросы получают контекст сервера, чтобы открытые потоки SSE
+	// закрывались при остановке, а не держали Shutdown до таймаута
+	httpServer := &http.Server{
+		Addr:        addr,
+		Handler:     srv.Handler(),
+		BaseContext: func(net.Listener) context.Context { return ctx },
+	}
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		httpServer.Shutdown(shutdownCtx)
+	}()
+
+	log.Printf("Онлайн результаты: http://localhost%s", addr)
+	if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Printf("Ошибка HTTP-сервера: %v", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,64 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Размер очереди сообщений одного подписчика. Если клиент не успевает
+// читать и очередь заполнена, новые сообщения для него отбрасываются
+const subscriberBuffer = 64
+
+// message - одно событие SSE
+type message struct {
+	name string
+	data interface{}
+}
+
+// writeTo записывает сообщение в формате text/event-stream
+func (m message) writeTo(w io.Writer) error {
+	data, err := json.Marshal(m.data)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", m.name, data)
+	return err
+}
+
+// broker рассылает сообщения всем подключенным клиентам
+type broker struct {
+	mu          sync.Mutex
+	subscribers map[chan message]struct{}
+}
+
+func newBroker() *broker {
+	return &broker{subscribers: make(map[chan message]struct{})}
+}
+
+func (b *broker) subscribe() chan message {
+	ch := make(chan message, subscriberBuffer)
+	b.mu.Lock()
+	b.subscribers[ch] = struct{}{}
+	b.mu.Unlock()
+	return ch
+}
+
+func (b *broker) unsubscribe(ch chan message) {
+	b.mu.Lock()
+	delete(b.subscribers, ch)
+	b.mu.Unlock()
+}
+
+func (b *broker) publish(m message) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subscribers {
+		select {
+		case ch <- m:
+		default:
+			// Медленный клиент: пропускаем сообщение, чтобы не блокировать гонку
+		}
+	}
+}
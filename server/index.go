@@ -0,0 +1,74 @@
+package server
+
+// indexHTML - простая страница для комментаторов: таблица обновляется
+// по событию standings, таблица команд эстафеты - по teams (вне эстафеты
+// она скрыта), лента - по остальным событиям потока
+const indexHTML = `<!DOCTYPE html>
+<html lang="ru">
+<head>
+<meta charset="utf-8">
+<title>Биатлон - онлайн результаты</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; }
+td, th { padding: 4px 12px; border-bottom: 1px solid #ddd; text-align: left; }
+#feed { margin-top: 2em; font-family: monospace; white-space: pre; }
+</style>
+</head>
+<body>
+<h1>Текущие результаты</h1>
+<table id="teams-table" hidden>
+<thead><tr><th>#</th><th>Команда</th><th>Статус</th><th>Время</th><th>Отставание</th><th>Этапы</th></tr></thead>
+<tbody id="teams"></tbody>
+</table>
+<table>
+<thead><tr><th>#</th><th>Участник</th><th>Статус</th><th>Круги</th><th>Последний круг</th><th>Время</th><th>Отставание</th><th>Стрельба</th></tr></thead>
+<tbody id="standings"></tbody>
+</table>
+<div id="feed"></div>
+<script>
+const stream = new EventSource("/api/stream");
+stream.addEventListener("standings", (e) => {
+  const body = document.getElementById("standings");
+  body.innerHTML = "";
+  for (const row of JSON.parse(e.data)) {
+    const tr = document.createElement("tr");
+    let status = row.skippedLoops ? row.status + " (пропущено штрафных кругов: " + row.skippedLoops + ")" : row.status;
+    if (row.suspectedLoops) status += " (возможно пропущено кругов: " + row.suspectedLoops + ")";
+    let athlete = row.name ? (row.bib ? "№" + row.bib + " " : "") + row.name + (row.nation ? " (" + row.nation + ")" : "") : row.athleteId;
+    if (row.leg) athlete += ", " + row.team + ", этап " + row.leg;
+    for (const value of [row.position, athlete, status, row.laps,
+        row.lastLap || "", row.time || "", row.gap || "", row.hits + "/" + row.shots + (row.misses ? " (" + row.misses + ")" : "")]) {
+      const td = document.createElement("td");
+      td.textContent = value;
+      tr.appendChild(td);
+    }
+    body.appendChild(tr);
+  }
+});
+stream.addEventListener("teams", (e) => {
+  document.getElementById("teams-table").hidden = false;
+  const body = document.getElementById("teams");
+  body.innerHTML = "";
+  for (const team of JSON.parse(e.data)) {
+    const tr = document.createElement("tr");
+    const legs = team.legs.map((leg) => leg.leg + ": " + (leg.split || leg.status || "-") + (leg.shooting ? " (" + leg.shooting + ")" : ""));
+    for (const value of [team.position, team.team + (team.nation ? " (" + team.nation + ")" : ""), team.status,
+        team.time || "", team.gap || "", legs.join("; ")]) {
+      const td = document.createElement("td");
+      td.textContent = value;
+      tr.appendChild(td);
+    }
+    body.appendChild(tr);
+  }
+});
+for (const name of ["event", "lap", "shot"]) {
+  stream.addEventListener(name, (e) => {
+    const feed = document.getElementById("feed");
+    feed.textContent = JSON.parse(e.data).text + "\n" + feed.textContent;
+  });
+}
+</script>
+</body>
+</html>
+`
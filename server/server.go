@@ -0,0 +1,360 @@
+package server
+
+import (
+	"biathlon-prototype/events"
+	"biathlon-prototype/models"
+	"biathlon-prototype/race"
+	"biathlon-prototype/utils"
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Server ведет гонку в реальном времени: принимает события по мере
+// поступления, обновляет race.Race и рассылает изменения подписчикам SSE
+type Server struct {
+	mu     sync.Mutex
+	race   *race.Race
+	broker *broker
+}
+
+// New создает сервер для уже созданной гонки
+func New(r *race.Race) *Server {
+	return &Server{
+		race:   r,
+		broker: newBroker(),
+	}
+}
+
+// Handler возвращает обработчик HTTP-запросов сервера
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /{$}", s.handleIndex)
+	mux.HandleFunc("GET /api/stream", s.handleStream)
+	mux.HandleFunc("GET /api/leaderboard", s.handleLeaderboard)
+	mux.HandleFunc("GET /api/teams", s.handleTeams)
+	mux.HandleFunc("GET /api/athletes/{id}", s.handleAthlete)
+	mux.HandleFunc("POST /api/events", s.handlePostEvents)
+	return mux
+}
+
+// IngestLine разбирает строку события и применяет ее к гонке.
+// Пустые строки пропускаются
+func (s *Server) IngestLine(line string) error {
+	if strings.TrimSpace(line) == "" {
+		return nil
+	}
+
+	event, err := events.ParseEvent(line)
+	if err != nil {
+		return err
+	}
+	return s.Ingest(event)
+}
+
+// Ingest применяет событие к гонке и рассылает подписчикам
+// появившиеся исходящие события и обновленную таблицу
+func (s *Server) Ingest(event events.Event) error {
+	s.mu.Lock()
+	logged := len(s.race.EventLog)
+	err := s.race.HandleEvent(event)
+
+	var messages []message
+	for _, out := range s.race.EventLog[logged:] {
+		messages = append(messages, outgoingMessage(s.race.ReportEvent(out)))
+	}
+	if len(messages) > 0 {
+		messages = append(messages, s.tables()...)
+	}
+	s.mu.Unlock()
+
+	for _, m := range messages {
+		s.broker.publish(m)
+	}
+	return err
+}
+
+// outgoingMessage выбирает имя SSE-события по типу исходящего события:
+// круги и стрельба рассылаются отдельными потоками, остальное - как event
+func outgoingMessage(e events.Outgoing) message {
+	name := "event"
+	switch e.Kind {
+	case events.KindLapFinished:
+		name = "lap"
+	case events.KindHit, events.KindMiss:
+		name = "shot"
+	}
+	return message{name: name, data: newEventJSON(e)}
+}
+
+// tables возвращает текущие таблицы для рассылки: standings, а в эстафете
+// еще и teams. Вызывается под s.mu
+func (s *Server) tables() []message {
+	tables := []message{{name: "standings", data: s.leaderboard()}}
+	if s.race.Format.Name() == race.FormatRelay {
+		tables = append(tables, message{name: "teams", data: s.race.TeamResults()})
+	}
+	return tables
+}
+
+// LeaderboardRow - строка текущей таблицы результатов
+type LeaderboardRow struct {
+	Position  int    `json:"position"`
+	Tied      bool   `json:"tied,omitempty"`
+	AthleteID int    `json:"athleteId"`
+	Status    string `json:"status"`
+	Laps      int    `json:"laps"`
+	LastLap   string `json:"lastLap,omitempty"`
+	Time      string `json:"time,omitempty"`
+	Gap       string `json:"gap,omitempty"`
+	Shots     int    `json:"shots"`
+	Hits      int    `json:"hits"`
+	Misses    string `json:"misses,omitempty"`         // Стрельба по рубежам, см. race.Race.ShootingString
+	Name      string `json:"name,omitempty"`           // Имя из ростера
+	Nation    string `json:"nation,omitempty"`         // Страна из ростера
+	Bib       int    `json:"bib,omitempty"`            // Нагрудный номер из ростера
+	Team      string `json:"team,omitempty"`           // Команда из ростера
+	Leg       int    `json:"leg,omitempty"`            // Этап эстафеты из ростера
+	Skipped   int    `json:"skippedLoops,omitempty"`   // Пропущенные штрафные круги
+	Suspected int    `json:"suspectedLoops,omitempty"` // Штрафные круги, пройденные подозрительно быстро
+}
+
+// leaderboard строит таблицу по текущему состоянию гонки. Вызывается под s.mu
+func (s *Server) leaderboard() []LeaderboardRow {
+	rows := make([]LeaderboardRow, 0, len(s.race.Athletes))
+	for _, standing := range s.race.Standings() {
+		a := standing.Athlete
+		row := LeaderboardRow{
+			Position:  standing.Rank,
+			Tied:      standing.Tied,
+			AthleteID: a.ID,
+			Status:    string(standing.Status),
+			Laps:      standing.Laps,
+			Shots:     standing.Shots,
+			Hits:      standing.Hits,
+			Misses:    standing.Shooting,
+			Skipped:   standing.SkippedLoops,
+			Suspected: standing.Suspected,
+		}
+		if a.Entry != nil {
+			row.Name, row.Nation, row.Bib = a.Entry.Name, a.Entry.Nation, a.Entry.Bib
+			row.Team, row.Leg = a.Entry.Team, a.Entry.Leg
+		}
+		if len(a.LapTimes) > 0 {
+			row.LastLap = utils.FormatDuration(a.LapTimes[len(a.LapTimes)-1])
+		}
+		if standing.Ranked {
+			row.Time = utils.FormatDuration(standing.OfficialTime)
+			row.Gap = utils.FormatDuration(standing.Gap)
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// AthleteDetail - подробная информация об участнике
+type AthleteDetail struct {
+	AthleteID    int                `json:"athleteId"`
+	Entry        *models.Entry      `json:"entry,omitempty"` // Заявка из ростера, если он загружен
+	Status       string             `json:"status"`
+	State        string             `json:"state"`
+	PlannedStart string             `json:"plannedStart,omitempty"`
+	ActualStart  string             `json:"actualStart,omitempty"`
+	Finish       string             `json:"finish,omitempty"`
+	Laps         []string           `json:"laps"`
+	Penalties    []string           `json:"penalties"`
+	Shots        int                `json:"shots"`
+	Hits         int                `json:"hits"`
+	Accuracy     float64            `json:"accuracy"`
+	Misses       string             `json:"misses,omitempty"`
+	Shooting     []race.StageResult `json:"shooting"`
+}
+
+func newAthleteDetail(r *race.Race, a *models.Athlete) AthleteDetail {
+	detail := AthleteDetail{
+		AthleteID: a.ID,
+		Entry:     a.Entry,
+		Status:    string(a.Status),
+		State:     string(a.State),
+		Laps:      formatDurations(a.LapTimes),
+		Penalties: formatDurations(a.PenaltyTimes),
+		Shots:     a.Shots,
+		Hits:      a.Hits,
+		Misses:    r.ShootingString(a),
+		Shooting:  race.StageResults(a),
+	}
+	if !a.StartTimePlanned.IsZero() {
+		detail.PlannedStart = utils.FormatTime(r.ReportTime(a.StartTimePlanned))
+	}
+	if a.StartTimeActual != nil {
+		detail.ActualStart = utils.FormatTime(r.ReportTime(*a.StartTimeActual))
+	}
+	if a.FinishTime != nil {
+		detail.Finish = utils.FormatTime(r.ReportTime(*a.FinishTime))
+	}
+	if a.Shots > 0 {
+		detail.Accuracy = float64(a.Hits) / float64(a.Shots) * 100
+	}
+	return detail
+}
+
+func formatDurations(durations []time.Duration) []string {
+	result := make([]string, 0, len(durations))
+	for _, d := range durations {
+		result = append(result, utils.FormatDuration(d))
+	}
+	return result
+}
+
+// eventJSON - исходящее событие в потоке SSE
+type eventJSON struct {
+	Time      string   `json:"time"`
+	Kind      int      `json:"kind"`
+	AthleteID int      `json:"athleteId"`
+	Params    []string `json:"params,omitempty"`
+	Text      string   `json:"text"`
+}
+
+func newEventJSON(e events.Outgoing) eventJSON {
+	return eventJSON{
+		Time:      utils.FormatTime(e.Time),
+		Kind:      int(e.Kind),
+		AthleteID: e.AthleteID,
+		Params:    e.Params(),
+		Text:      events.TextRenderer{}.Render(e),
+	}
+}
+
+func (s *Server) handleLeaderboard(w http.ResponseWriter, _ *http.Request) {
+	s.mu.Lock()
+	rows := s.leaderboard()
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, rows)
+}
+
+// handleTeams отдает итоги команд эстафеты (race.TeamResult); вне эстафеты - пустой список
+func (s *Server) handleTeams(w http.ResponseWriter, _ *http.Request) {
+	s.mu.Lock()
+	teams := s.race.TeamResults()
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, teams)
+}
+
+func (s *Server) handleAthlete(w http.ResponseWriter, req *http.Request) {
+	id, err := strconv.Atoi(req.PathValue("id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("некорректный ID участника: %q", req.PathValue("id")))
+		return
+	}
+
+	s.mu.Lock()
+	athlete, exists := s.race.Athletes[id]
+	var detail AthleteDetail
+	if exists {
+		detail = newAthleteDetail(s.race, athlete)
+	}
+	s.mu.Unlock()
+
+	if !exists {
+		writeError(w, http.StatusNotFound, fmt.Errorf("участник %d не найден", id))
+		return
+	}
+	writeJSON(w, http.StatusOK, detail)
+}
+
+// ingestResult - ответ на загрузку событий
+type ingestResult struct {
+	Accepted int      `json:"accepted"`
+	Errors   []string `json:"errors,omitempty"`
+}
+
+// handlePostEvents принимает одно или несколько событий, по одному на строку
+func (s *Server) handlePostEvents(w http.ResponseWriter, req *http.Request) {
+	var result ingestResult
+
+	scanner := bufio.NewScanner(req.Body)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if err := s.IngestLine(line); err != nil {
+			result.Errors = append(result.Errors, fmt.Sprintf("строка %d: %v", lineNumber, err))
+			continue
+		}
+		result.Accepted++
+	}
+	if err := scanner.Err(); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	status := http.StatusOK
+	if len(result.Errors) > 0 {
+		status = http.StatusUnprocessableEntity
+	}
+	writeJSON(w, status, result)
+}
+
+// handleStream отдает поток Server-Sent Events. Сразу после подключения
+// клиент получает текущую таблицу, затем - изменения по мере поступления событий
+func (s *Server) handleStream(w http.ResponseWriter, req *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("потоковая передача не поддерживается"))
+		return
+	}
+
+	messages := s.broker.subscribe()
+	defer s.broker.unsubscribe(messages)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	s.mu.Lock()
+	initial := s.tables()
+	s.mu.Unlock()
+	for _, m := range initial {
+		if err := m.writeTo(w); err != nil {
+			return
+		}
+	}
+	flusher.Flush()
+
+	for {
+		select {
+		case <-req.Context().Done():
+			return
+		case m := <-messages:
+			if err := m.writeTo(w); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+func (s *Server) handleIndex(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	io.WriteString(w, indexHTML)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,323 @@
+package server
+
+import (
+	"biathlon-prototype/configs"
+	"biathlon-prototype/models"
+	"biathlon-prototype/race"
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testEvents = `[09:00:00.000] 1 1
+[09:05:00.000] 2 1 09:30:00.000
+[09:29:00.000] 3 1
+[09:30:00.000] 4 1
+[09:45:00.000] 5 1 1
+[09:45:05.000] 6 1 1
+[09:45:06.000] 61 1 2
+[09:45:07.000] 7 1
+[10:00:00.000] 10 1
+[10:25:00.000] 10 1
+[10:25:01.000] 33 1
+`
+
+func TestPostEventsAndLeaderboard(t *testing.T) {
+	srv := createTestServer(t)
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	resp, err := http.Post(ts.URL+"/api/events", "text/plain", strings.NewReader(testEvents))
+	if err != nil {
+		t.Fatalf("POST /api/events error = %v", err)
+	}
+	var result ingestResult
+	decodeJSON(t, resp, &result)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("POST /api/events status = %d, errors = %v", resp.StatusCode, result.Errors)
+	}
+	if result.Accepted != 11 {
+		t.Errorf("Accepted = %d, want 11", result.Accepted)
+	}
+
+	resp, err = http.Get(ts.URL + "/api/leaderboard")
+	if err != nil {
+		t.Fatalf("GET /api/leaderboard error = %v", err)
+	}
+	var rows []LeaderboardRow
+	decodeJSON(t, resp, &rows)
+
+	if len(rows) != 1 {
+		t.Fatalf("Expected 1 row, got %d", len(rows))
+	}
+	row := rows[0]
+	if row.AthleteID != 1 || row.Status != "Finished" || row.Laps != 2 {
+		t.Errorf("Unexpected row %+v", row)
+	}
+	// Промах без штрафного круга: к 55:01 добавляется штраф за пропущенный круг
+	if row.Time != "00:57:01.000" || row.Gap != "00:00:00.000" {
+		t.Errorf("Time/Gap = %s/%s, want 00:57:01.000/00:00:00.000", row.Time, row.Gap)
+	}
+	if row.Shots != 2 || row.Hits != 1 || row.Misses != "1 = 1" {
+		t.Errorf("Shooting = %d/%d (%s), want 1/2 (1 = 1)", row.Hits, row.Shots, row.Misses)
+	}
+}
+
+func TestPostEvents_Errors(t *testing.T) {
+	srv := createTestServer(t)
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	body := "[09:00:00.000] 1 1\nнекорректная строка\n[09:01:00.000] 4 1\n"
+	resp, err := http.Post(ts.URL+"/api/events", "text/plain", strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("POST /api/events error = %v", err)
+	}
+	var result ingestResult
+	decodeJSON(t, resp, &result)
+
+	if resp.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("Status = %d, want %d", resp.StatusCode, http.StatusUnprocessableEntity)
+	}
+	// Разбор второй строки не удался, третья нарушает порядок событий
+	if result.Accepted != 1 || len(result.Errors) != 2 {
+		t.Errorf("Result = %+v, want 1 accepted and 2 errors", result)
+	}
+}
+
+func TestAthleteDetail(t *testing.T) {
+	srv := createTestServer(t)
+	for _, line := range strings.Split(testEvents, "\n") {
+		if err := srv.IngestLine(line); err != nil {
+			t.Fatalf("IngestLine(%q) error = %v", line, err)
+		}
+	}
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/api/athletes/1")
+	if err != nil {
+		t.Fatalf("GET /api/athletes/1 error = %v", err)
+	}
+	var detail AthleteDetail
+	decodeJSON(t, resp, &detail)
+
+	if detail.PlannedStart != "09:30:00.000" || detail.Finish != "10:25:01.000" {
+		t.Errorf("Unexpected times in %+v", detail)
+	}
+	if len(detail.Laps) != 2 || detail.Laps[0] != "00:30:00.000" {
+		t.Errorf("Laps = %v, want [00:30:00.000 00:25:00.000]", detail.Laps)
+	}
+
+	for path, want := range map[string]int{
+		"/api/athletes/2":   http.StatusNotFound,
+		"/api/athletes/abc": http.StatusBadRequest,
+	} {
+		resp, err := http.Get(ts.URL + path)
+		if err != nil {
+			t.Fatalf("GET %s error = %v", path, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Errorf("GET %s status = %d, want %d", path, resp.StatusCode, want)
+		}
+	}
+}
+
+func TestStream(t *testing.T) {
+	srv := createTestServer(t)
+	ts := httptest.NewServer(srv.Handler())
+	defer ts.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL+"/api/stream", nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET /api/stream error = %v", err)
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Content-Type = %q, want text/event-stream", ct)
+	}
+
+	reader := bufio.NewReader(resp.Body)
+	if name := readEventName(t, reader); name != "standings" {
+		t.Fatalf("First event = %q, want standings", name)
+	}
+
+	srv.IngestLine("[09:00:00.000] 1 1")
+
+	var names []string
+	for len(names) < 2 {
+		names = append(names, readEventName(t, reader))
+	}
+	if names[0] != "event" || names[1] != "standings" {
+		t.Errorf("Events = %v, want [event standings]", names)
+	}
+}
+
+func TestFollow(t *testing.T) {
+	srv := createTestServer(t)
+	pr, pw := io.Pipe()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- srv.Follow(ctx, pr, 10*time.Millisecond, func(line string, err error) {
+			t.Errorf("Unexpected error for %q: %v", line, err)
+		})
+	}()
+
+	// Строка, записанная частями, применяется только после перевода строки
+	io.WriteString(pw, "[09:00:00.000] 1")
+	io.WriteString(pw, " 1\n")
+	pw.Close()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		srv.mu.Lock()
+		_, registered := srv.race.Athletes[1]
+		srv.mu.Unlock()
+		if registered {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Athlete was not registered from followed input")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Follow() error = %v", err)
+	}
+}
+
+func createTestServer(t *testing.T) *Server {
+	t.Helper()
+	r, err := race.NewRace(configs.Config{
+		Laps:        2,
+		LapLen:      3651,
+		PenaltyLen:  50,
+		FiringLines: 1,
+		Start:       "09:30:00",
+		StartDelta:  "00:00:30",
+	})
+	if err != nil {
+		t.Fatalf("NewRace() error = %v", err)
+	}
+	r.Output = io.Discard
+	return New(r)
+}
+
+func decodeJSON(t *testing.T, resp *http.Response, v interface{}) {
+	t.Helper()
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		t.Fatalf("Failed to decode response: %v", err)
+	}
+}
+
+// readEventName читает одно событие SSE и возвращает его имя
+func readEventName(t *testing.T, reader *bufio.Reader) string {
+	t.Helper()
+	name := ""
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Failed to read stream: %v", err)
+		}
+		line = strings.TrimRight(line, "\n")
+		if line == "" {
+			return name
+		}
+		if strings.HasPrefix(line, "event: ") {
+			name = strings.TrimPrefix(line, "event: ")
+		}
+	}
+}
+
+func TestRelayTeams(t *testing.T) {
+	r, err := race.NewRace(configs.Config{
+		Laps:        1,
+		LapLen:      2500,
+		PenaltyLen:  150,
+		FiringLines: 1,
+		Start:       "10:00:00",
+		StartDelta:  "00:00:00",
+		Format:      race.FormatRelay,
+		Legs:        2,
+	})
+	if err != nil {
+		t.Fatalf("NewRace() error = %v", err)
+	}
+	r.Output = io.Discard
+	err = r.SetRoster([]models.Entry{
+		{ID: 11, Name: "A", Nation: "NOR", Team: "Norway", Leg: 1},
+		{ID: 12, Name: "B", Nation: "NOR", Team: "Norway", Leg: 2},
+	})
+	if err != nil {
+		t.Fatalf("SetRoster() error = %v", err)
+	}
+	ts := httptest.NewServer(New(r).Handler())
+	defer ts.Close()
+
+	relayEvents := `[09:00:00.000] 1 11
+[09:00:00.000] 1 12
+[09:59:00.000] 3 11
+[10:00:00.000] 4 11
+[10:05:00.000] 5 11 1
+[10:05:10.000] 61 11 1
+[10:05:12.000] 6 11 2
+[10:05:14.000] 6 11 3
+[10:05:16.000] 6 11 4
+[10:05:18.000] 6 11 5
+[10:05:20.000] 13 11
+[10:05:25.000] 6 11 1
+[10:05:40.000] 7 11
+[10:20:00.000] 10 11
+[10:20:00.000] 12 11 12
+`
+	resp, err := http.Post(ts.URL+"/api/events", "text/plain", strings.NewReader(relayEvents))
+	if err != nil {
+		t.Fatalf("POST /api/events error = %v", err)
+	}
+	var result ingestResult
+	decodeJSON(t, resp, &result)
+	if len(result.Errors) != 0 {
+		t.Fatalf("POST /api/events errors = %v", result.Errors)
+	}
+
+	resp, err = http.Get(ts.URL + "/api/teams")
+	if err != nil {
+		t.Fatalf("GET /api/teams error = %v", err)
+	}
+	var teams []race.TeamResult
+	decodeJSON(t, resp, &teams)
+	if len(teams) != 1 || teams[0].Team != "Norway" || len(teams[0].Legs) != 2 {
+		t.Fatalf("Teams = %+v, want Norway with 2 legs", teams)
+	}
+	if leg := teams[0].Legs[0]; leg.Split != "00:20:00.000" || leg.Shooting != "0+1" {
+		t.Errorf("Leg 1 = %+v, want split 00:20:00.000, shooting 0+1", leg)
+	}
+
+	resp, err = http.Get(ts.URL + "/api/leaderboard")
+	if err != nil {
+		t.Fatalf("GET /api/leaderboard error = %v", err)
+	}
+	var rows []LeaderboardRow
+	decodeJSON(t, resp, &rows)
+	for _, row := range rows {
+		if row.AthleteID == 11 && (row.Team != "Norway" || row.Leg != 1 || row.Misses != "0+1") {
+			t.Errorf("Athlete 11 row = %+v, want Norway leg 1, shooting 0+1", row)
+		}
+	}
+}
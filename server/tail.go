@@ -0,0 +1,50 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"os"
+	"time"
+)
+
+// Tail читает файл событий с начала и продолжает следить за дописываемыми
+// строками, пока не отменен ctx. Ошибки разбора отдельных строк передаются
+// в onError и не останавливают чтение
+func (s *Server) Tail(ctx context.Context, path string, poll time.Duration, onError func(line string, err error)) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return s.Follow(ctx, file, poll, onError)
+}
+
+// Follow читает события из input построчно. Дойдя до конца, ждет poll
+// и пробует читать снова: так обрабатываются строки, дописанные позже.
+// Неполная последняя строка ждет своего перевода строки
+func (s *Server) Follow(ctx context.Context, input io.Reader, poll time.Duration, onError func(line string, err error)) error {
+	reader := bufio.NewReader(input)
+	pending := ""
+	for {
+		chunk, err := reader.ReadString('\n')
+		pending += chunk
+		if err == nil {
+			line := pending[:len(pending)-1]
+			pending = ""
+			if ingestErr := s.IngestLine(line); ingestErr != nil {
+				onError(line, ingestErr)
+			}
+			continue
+		}
+		if err != io.EOF {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(poll):
+		}
+	}
+}
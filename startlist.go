@@ -0,0 +1,134 @@
+package main
+
+import (
+	"biathlon-prototype/events"
+	"biathlon-prototype/race"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+func runStartList(args []string) int {
+	var draw bool
+	var seed int64
+	var lotteryPath string
+	opts, err := parseOptions("startlist", args, func(fs *flag.FlagSet) {
+		fs.BoolVar(&draw, "draw", false, "провести жеребьевку среди зарегистрированных участников вместо событий 2")
+		fs.Int64Var(&seed, "seed", 0, "стартовое значение жеребьевки (по умолчанию случайное, печатается в stderr)")
+		fs.StringVar(&lotteryPath, "lottery", "", "записать протокол в файл событиями жеребьевки (2)")
+	})
+	if err != nil {
+		return exitCode(err)
+	}
+
+	r, err := loadRace(opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	r.Output = io.Discard
+
+	// При общем старте протокол можно только вывести: событий 2 гонка не примет
+	if draw || lotteryPath != "" {
+		if err := r.CheckLottery(); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+
+	input, err := openEvents(opts.eventsPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Ошибка открытия файла событий: %v\n", err)
+		return 1
+	}
+	defer input.Close()
+
+	_, err = processEvents(r, input, func(lineNumber int, line string, err error) {
+		fmt.Fprintf(os.Stderr, "Строка %d: %v (содержимое: %q)\n", lineNumber, err, line)
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Ошибка чтения событий: %v\n", err)
+		return 1
+	}
+
+	var list []race.StartListEntry
+	exit := 0
+	if draw {
+		if seed == 0 {
+			seed = time.Now().UnixNano()
+		}
+		fmt.Fprintf(os.Stderr, "Жеребьевка, seed %d\n", seed)
+		list, err = r.DrawStartList(seed)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	} else {
+		var problems []race.StartListProblem
+		list, problems = r.StartList()
+		for _, problem := range problems {
+			fmt.Fprintln(os.Stderr, problem)
+		}
+		if len(problems) > 0 {
+			exit = 1
+		}
+	}
+
+	if err := writeStartList(os.Stdout, r, list, opts.format); err != nil {
+		fmt.Fprintf(os.Stderr, "Ошибка вывода стартового протокола: %v\n", err)
+		return 1
+	}
+
+	if lotteryPath != "" {
+		if err := writeLottery(lotteryPath, r, list); err != nil {
+			fmt.Fprintf(os.Stderr, "Ошибка записи событий жеребьевки: %v\n", err)
+			return 1
+		}
+	}
+	return exit
+}
+
+// writeStartList выводит стартовый протокол в выбранном формате
+func writeStartList(w io.Writer, r *race.Race, list []race.StartListEntry, format string) error {
+	switch format {
+	case "json":
+		return r.WriteStartListJSON(w, list)
+	case "csv":
+		return r.WriteStartListCSV(w, list)
+	}
+	r.WriteStartList(w, list)
+	return nil
+}
+
+// writeLottery записывает стартовый протокол в файл событиями жеребьевки.
+// Время событий - последняя регистрация, чтобы жеребьевка шла после нее
+func writeLottery(path string, r *race.Race, list []race.StartListEntry) error {
+	// Времена без даты относятся к нулевому году, поэтому нулевое time.Time не годится как минимум
+	at := r.StartTime
+	found := false
+	for _, athlete := range r.Athletes {
+		if !athlete.RegisteredAt.IsZero() && (!found || athlete.RegisteredAt.After(at)) {
+			at, found = athlete.RegisteredAt, true
+		}
+	}
+
+	return writeEvents(path, race.LotteryEvents(list, at))
+}
+
+// writeEvents записывает события в файл строками входного формата
+func writeEvents(path string, list []events.Event) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	for _, event := range list {
+		if _, err := fmt.Fprintln(file, event); err != nil {
+			return err
+		}
+	}
+	return file.Close()
+}
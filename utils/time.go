@@ -2,16 +2,42 @@ package utils
 
 import (
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 )
 
-const timeLayout = "15:04:05.000"
+const (
+	timeLayout     = "15:04:05.000"
+	dateTimeLayout = "2006-01-02T15:04:05.000"
+	DateLayout     = "2006-01-02"
+)
+
+// RolloverThreshold - насколько время может уйти назад, прежде чем
+// считается, что наступили следующие сутки. Меньшие откаты считаются
+// обычным беспорядком в событиях
+const RolloverThreshold = 12 * time.Hour
 
+// ParseTime разбирает время ЧЧ:ММ:СС.ммм или дату со временем
+// ГГГГ-ММ-ДДTЧЧ:ММ:СС.ммм. Время без даты получает нулевой год
 func ParseTime(value string) (time.Time, error) {
+	if len(value) > len(timeLayout) {
+		return time.Parse(dateTimeLayout, value)
+	}
 	return time.Parse(timeLayout, value)
 }
 
+// HasDate сообщает, что у времени есть календарная дата
+func HasDate(t time.Time) bool {
+	return t.Year() != 0
+}
+
+// FormatTime выводит время как ЧЧ:ММ:СС.ммм, а если у него есть дата -
+// как ГГГГ-ММ-ДДTЧЧ:ММ:СС.ммм, чтобы результат снова разбирался ParseTime
 func FormatTime(t time.Time) string {
+	if HasDate(t) {
+		return t.Format(dateTimeLayout)
+	}
 	return t.Format(timeLayout)
 }
 
@@ -22,3 +48,91 @@ func FormatDuration(d time.Duration) string {
 	milliseconds := int(d.Milliseconds()) % 1000
 	return fmt.Sprintf("%02d:%02d:%02d.%03d", hours, minutes, seconds, milliseconds)
 }
+
+// ParseDuration разбирает длительность ЧЧ:ММ:СС.ммм, как ее выводит FormatDuration
+func ParseDuration(value string) (time.Duration, error) {
+	var h, m, s, ms int
+	if n, err := fmt.Sscanf(value, "%d:%d:%d.%d", &h, &m, &s, &ms); err != nil || n != 4 || len(value) < len("00:00:00.000") {
+		return 0, fmt.Errorf("некорректная длительность %q, ожидается ЧЧ:ММ:СС.ммм", value)
+	}
+	return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute +
+		time.Duration(s)*time.Second + time.Duration(ms)*time.Millisecond, nil
+}
+
+// ParseDelta разбирает интервал ЧЧ:ММ:СС или длительность в формате
+// time.ParseDuration (например 30s)
+func ParseDelta(value string) (time.Duration, error) {
+	parts := strings.Split(value, ":")
+	if len(parts) == 3 {
+		h, errH := strconv.Atoi(parts[0])
+		m, errM := strconv.Atoi(parts[1])
+		s, errS := strconv.Atoi(parts[2])
+		if errH != nil || errM != nil || errS != nil {
+			return 0, fmt.Errorf("некорректный интервал %q, ожидается ЧЧ:ММ:СС", value)
+		}
+		return time.Duration(h)*time.Hour + time.Duration(m)*time.Minute + time.Duration(s)*time.Second, nil
+	}
+	return time.ParseDuration(value)
+}
+
+// OnDay переносит время суток t на календарный день day в его часовом поясе
+func OnDay(t, day time.Time) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(),
+		t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), day.Location())
+}
+
+// InZone возвращает момент с теми же датой и временем суток, что у t,
+// но в часовом поясе loc: настенное время читается как местное
+func InZone(t time.Time, loc *time.Location) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(),
+		t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), loc)
+}
+
+// After возвращает время t без даты, привязанное ко дню ref, или к следующему
+// дню, если t раньше ref больше чем на RolloverThreshold. Поэтому результат
+// может быть немного раньше ref. Время с датой берется как есть,
+// в часовом поясе ref
+func After(t, ref time.Time) time.Time {
+	if HasDate(t) {
+		return InZone(t, ref.Location())
+	}
+	result := OnDay(t, ref)
+	if result.Before(ref.Add(-RolloverThreshold)) {
+		result = result.AddDate(0, 0, 1)
+	}
+	return result
+}
+
+// DayClock привязывает время событий без даты к календарным дням.
+// События идут по порядку, поэтому резкий откат времени назад
+// (больше RolloverThreshold) означает переход через полночь
+type DayClock struct {
+	last time.Time
+}
+
+// NewDayClock создает часы, начинающиеся с момента start
+// (обычно полночь дня гонки)
+func NewDayClock(start time.Time) *DayClock {
+	return &DayClock{last: start}
+}
+
+// Resolve возвращает полное время события и запоминает его (см. Peek и Commit)
+func (c *DayClock) Resolve(t time.Time) time.Time {
+	result := c.Peek(t)
+	c.Commit(result)
+	return result
+}
+
+// Peek возвращает полное время события, не меняя часы. Время с датой
+// принимается как есть
+func (c *DayClock) Peek(t time.Time) time.Time {
+	return After(t, c.last)
+}
+
+// Commit запоминает полное время принятого события: если оно позже
+// предыдущих, оно задает день для следующих событий
+func (c *DayClock) Commit(t time.Time) {
+	if t.After(c.last) {
+		c.last = t
+	}
+}
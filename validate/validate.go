@@ -0,0 +1,267 @@
+package validate
+
+import (
+	"biathlon-prototype/configs"
+	"biathlon-prototype/events"
+	"biathlon-prototype/models"
+	"biathlon-prototype/race"
+	"biathlon-prototype/utils"
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Severity - важность найденной проблемы
+type Severity string
+
+const (
+	SeverityError   Severity = "error"   // Файл нельзя принимать
+	SeverityWarning Severity = "warning" // Подозрительно, но результаты можно посчитать
+)
+
+// Diagnostic - проблема в конкретном месте файла событий
+type Diagnostic struct {
+	Line     int // Номер строки, начиная с 1
+	Column   int // Номер символа в строке, начиная с 1
+	Severity Severity
+	Message  string
+}
+
+func (d Diagnostic) String() string {
+	label := "ошибка"
+	if d.Severity == SeverityWarning {
+		label = "предупреждение"
+	}
+	return fmt.Sprintf("%d:%d: %s: %s", d.Line, d.Column, label, d.Message)
+}
+
+// HasErrors сообщает, есть ли среди диагностик ошибки
+func HasErrors(diagnostics []Diagnostic) bool {
+	for _, d := range diagnostics {
+		if d.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// field - слово строки события и его позиция
+type field struct {
+	text   string
+	column int
+}
+
+// splitFields делит строку на слова так же, как strings.Fields,
+// запоминая колонку начала каждого слова. Комментарий после # отбрасывается
+func splitFields(line string) []field {
+	var result []field
+	column := 0
+	start := -1
+	for i, ch := range line {
+		column++
+		if ch == '#' && start < 0 {
+			break
+		}
+		if unicode.IsSpace(ch) {
+			if start >= 0 {
+				result = append(result, field{text: line[start:i], column: column - utf8.RuneCountInString(line[start:i])})
+				start = -1
+			}
+			continue
+		}
+		if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		result = append(result, field{text: line[start:], column: column - utf8.RuneCountInString(line[start:]) + 1})
+	}
+	return result
+}
+
+// validator хранит состояние проверки между строками
+type validator struct {
+	cfg         configs.Config
+	race        *race.Race
+	diagnostics []Diagnostic
+	clock       *utils.DayClock
+	lastTime    time.Time
+	lastLine    int
+}
+
+// Events проверяет файл событий построчно: формат строки, известность
+// событий, наличие и корректность параметров (в том числе номера рубежа
+// по конфигурации), порядок времени и допустимость события в текущем
+// состоянии участника. Если передан ростер, участники не из него
+// отмечаются предупреждением
+func Events(input io.Reader, cfg configs.Config, roster []models.Entry) ([]Diagnostic, error) {
+	r, err := race.NewRace(cfg)
+	if err != nil {
+		return nil, err
+	}
+	r.Output = io.Discard
+	if roster != nil {
+		if err := r.SetRoster(roster); err != nil {
+			return nil, err
+		}
+	} else if r.Format.Name() == race.FormatRelay {
+		return nil, fmt.Errorf("для эстафеты нужен ростер с командами и этапами")
+	}
+
+	v := &validator{cfg: cfg, race: r, clock: utils.NewDayClock(r.Date)}
+
+	scanner := bufio.NewScanner(input)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		v.checkLine(lineNumber, scanner.Text())
+	}
+
+	sort.SliceStable(v.diagnostics, func(i, j int) bool {
+		a, b := v.diagnostics[i], v.diagnostics[j]
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return v.diagnostics, scanner.Err()
+}
+
+func (v *validator) report(line, column int, severity Severity, format string, args ...interface{}) {
+	v.diagnostics = append(v.diagnostics, Diagnostic{
+		Line:     line,
+		Column:   column,
+		Severity: severity,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+func (v *validator) checkLine(lineNumber int, line string) {
+	fields := splitFields(line)
+	if len(fields) == 0 {
+		return // Пустая строка или только комментарий
+	}
+	if len(fields) < 3 {
+		last := fields[len(fields)-1]
+		v.report(lineNumber, last.column+utf8.RuneCountInString(last.text), SeverityError,
+			"ожидается [время] ID-события ID-участника, найдено полей: %d", len(fields))
+		return
+	}
+
+	timeField, idField, athleteField, params := fields[0], fields[1], fields[2], fields[3:]
+
+	if !strings.HasPrefix(timeField.text, "[") || !strings.HasSuffix(timeField.text, "]") {
+		v.report(lineNumber, timeField.column, SeverityError, "время должно быть в квадратных скобках: %q", timeField.text)
+		return
+	}
+	eventTime, err := utils.ParseTime(strings.Trim(timeField.text, "[]"))
+	if err != nil {
+		v.report(lineNumber, timeField.column+1, SeverityError, "некорректное время %q, ожидается ЧЧ:ММ:СС.ммм", strings.Trim(timeField.text, "[]"))
+		return
+	}
+
+	// Откат больше чем на RolloverThreshold считается переходом через полночь
+	resolved := v.clock.Resolve(eventTime)
+	if !v.lastTime.IsZero() && resolved.Before(v.lastTime) {
+		v.report(lineNumber, timeField.column+1, SeverityError, "время %s меньше времени предыдущего события %s (строка %d)",
+			utils.FormatTime(eventTime), utils.FormatTime(v.lastTime), v.lastLine)
+	} else {
+		v.lastTime = resolved
+		v.lastLine = lineNumber
+	}
+
+	eventID, err := strconv.Atoi(idField.text)
+	if err != nil {
+		v.report(lineNumber, idField.column, SeverityError, "ID события должен быть числом: %q", idField.text)
+		return
+	}
+	rule, known := race.Params(eventID)
+	if !known {
+		v.report(lineNumber, idField.column, SeverityError, "неизвестное событие %d", eventID)
+		return
+	}
+
+	athleteID, err := strconv.Atoi(athleteField.text)
+	if err != nil || athleteID <= 0 {
+		v.report(lineNumber, athleteField.column, SeverityError, "ID участника должен быть положительным числом: %q", athleteField.text)
+		return
+	}
+
+	event := events.Event{Time: eventTime, EventID: eventID, AthleteID: athleteID, Raw: line}
+	for _, p := range params {
+		event.Params = append(event.Params, p.text)
+	}
+
+	// Значения параметров проверяет гонка, здесь - только колонка для отчета
+	if _, err := v.race.CheckParams(event); err != nil {
+		v.report(lineNumber, v.paramColumn(athleteField, params, err), SeverityError, "событие %d: %s", eventID, err)
+		return
+	}
+	if len(params) < rule.Required {
+		v.report(lineNumber, v.paramColumn(athleteField, params, nil), SeverityWarning,
+			"у события %d нет обязательного параметра: %s", eventID, rule.Missing)
+	}
+	if allowed := rule.Required + rule.Optional; len(params) > allowed && !rule.Extra {
+		v.report(lineNumber, params[allowed].column, SeverityWarning, "лишние параметры у события %d", eventID)
+	}
+	v.checkConfigParams(lineNumber, eventID, resolved, params)
+
+	// Прогоняем событие через гонку, чтобы найти нарушения порядка событий
+	logged := len(v.race.EventLog)
+	if err := v.race.HandleEvent(event); err != nil {
+		reason := err.Error()
+		var violation race.Violation
+		if errors.As(err, &violation) {
+			reason = violation.Reason
+		}
+		v.report(lineNumber, idField.column, SeverityError, "событие %d недопустимо для участника %d: %s",
+			eventID, athleteID, reason)
+		return
+	}
+
+	// Сверка штрафных кругов происходит, когда участник уходит дальше по дистанции
+	for _, out := range v.race.EventLog[logged:] {
+		switch p := out.Payload.(type) {
+		case events.PenaltySkipped:
+			v.report(lineNumber, idField.column, SeverityWarning,
+				"участник %d прошел штрафных кругов меньше, чем промахов после стрельбы %d (положено: %d, пройдено: %d)",
+				athleteID, p.Stage, p.Owed, p.Served)
+		case events.PenaltySuspect:
+			v.report(lineNumber, idField.column, SeverityWarning,
+				"участник %d прошел штрафные круги после стрельбы %d слишком быстро (время: %s, минимум: %s)",
+				athleteID, p.Stage, utils.FormatDuration(p.Served), utils.FormatDuration(p.Minimum))
+		}
+		if out.Kind == events.KindNotInRoster {
+			v.report(lineNumber, idField.column, SeverityWarning, "участник %d отсутствует в ростере", athleteID)
+		}
+	}
+}
+
+// paramColumn возвращает колонку параметра, в котором ошибка err
+// (ParamError). Если параметра нет, - колонку сразу после ID участника
+func (v *validator) paramColumn(athleteField field, params []field, err error) int {
+	var perr race.ParamError
+	if errors.As(err, &perr) && perr.Index < len(params) {
+		return params[perr.Index].column
+	}
+	return athleteField.column + utf8.RuneCountInString(athleteField.text)
+}
+
+// checkConfigParams проверяет параметры, корректность которых зависит
+// от времени события: время старта относительно жеребьевки.
+// Такие ошибки не мешают применить событие
+func (v *validator) checkConfigParams(lineNumber, eventID int, eventTime time.Time, params []field) {
+	if eventID == events.EventStartTimeLottery {
+		startTime, _ := utils.ParseTime(params[0].text)
+		if utils.After(startTime, eventTime).Before(eventTime) {
+			v.report(lineNumber, params[0].column, SeverityWarning, "время старта %s раньше времени жеребьевки", params[0].text)
+		}
+	}
+}
@@ -0,0 +1,285 @@
+package validate
+
+import (
+	"biathlon-prototype/configs"
+	"biathlon-prototype/models"
+	"strings"
+	"testing"
+)
+
+func testConfig() configs.Config {
+	return configs.Config{
+		Laps:        2,
+		LapLen:      3651,
+		PenaltyLen:  50,
+		FiringLines: 1,
+		Start:       "09:30:00",
+		StartDelta:  "00:00:30",
+	}
+}
+
+func TestEvents_ValidFile(t *testing.T) {
+	const input = `[09:00:00.000] 1 1
+[09:05:00.000] 2 1 09:30:00.000
+[09:29:00.000] 3 1
+[09:30:00.000] 4 1
+
+[09:45:00.000] 5 1 1
+[09:45:05.000] 6 1 1   # Попадание в мишень 1
+[09:45:06.000] 61 1 2  # Промах по мишени 2
+[09:45:07.000] 7 1
+[09:45:10.000] 8 1
+[09:46:30.000] 9 1
+[10:00:00.000] 10 1
+[10:25:00.000] 10 1
+[10:25:01.000] 33 1
+`
+
+	diagnostics, err := Events(strings.NewReader(input), testConfig(), nil)
+	if err != nil {
+		t.Fatalf("Events() error = %v", err)
+	}
+	if len(diagnostics) != 0 {
+		t.Errorf("Expected no diagnostics, got %v", diagnostics)
+	}
+}
+
+func TestEvents_Diagnostics(t *testing.T) {
+	// Участник 1 зарегистрирован и готов к старту, проверяемая строка идет последней
+	const prefix = "[09:00:00.000] 1 1\n[09:05:00.000] 2 1 09:30:00.000\n"
+
+	testCases := []struct {
+		name     string
+		line     string
+		column   int
+		severity Severity
+		message  string
+	}{
+		{name: "Too few fields", line: "[09:10:00.000] 3", column: 17, severity: SeverityError, message: "ожидается"},
+		{name: "Time without brackets", line: "09:10:00.000 3 1", column: 1, severity: SeverityError, message: "квадратных скобках"},
+		{name: "Bad time", line: "[9:10] 3 1", column: 2, severity: SeverityError, message: "некорректное время"},
+		{name: "Time goes back", line: "[08:00:00.000] 3 1", column: 2, severity: SeverityError, message: "меньше времени предыдущего"},
+		{name: "Event ID not a number", line: "[09:10:00.000] x 1", column: 16, severity: SeverityError, message: "ID события"},
+		{name: "Unknown event", line: "[09:10:00.000] 14 1", column: 16, severity: SeverityError, message: "неизвестное событие 14"},
+		{name: "Bad athlete ID", line: "[09:10:00.000] 3 -1", column: 18, severity: SeverityError, message: "ID участника"},
+		{name: "Missing start time", line: "[09:10:00.000] 2 1", column: 19, severity: SeverityError, message: "обязательного параметра"},
+		{name: "Bad start time", line: "[09:10:00.000] 2 1 later", column: 20, severity: SeverityError, message: "время старта"},
+		{name: "Extra params", line: "[09:10:00.000] 3 1 lane7", column: 20, severity: SeverityWarning, message: "лишние параметры"},
+		{name: "Bad lane", line: "[09:10:00.000] 5 1 1 x", column: 22, severity: SeverityError, message: "номер стрелковой позиции"},
+		{name: "Target out of range", line: "[09:10:00.000] 6 1 7", column: 20, severity: SeverityError, message: "мишень 7 вне диапазона"},
+		{name: "Bad photo finish place", line: "[09:10:00.000] 33 1 0", column: 21, severity: SeverityError, message: "фотофинишу"},
+		{name: "Out of order event", line: "[09:10:00.000] 33 1", column: 16, severity: SeverityError, message: "недопустимо"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			diagnostics, err := Events(strings.NewReader(prefix+tc.line+"\n"), testConfig(), nil)
+			if err != nil {
+				t.Fatalf("Events() error = %v", err)
+			}
+			if len(diagnostics) != 1 {
+				t.Fatalf("Expected 1 diagnostic, got %v", diagnostics)
+			}
+
+			d := diagnostics[0]
+			if d.Line != 3 || d.Column != tc.column || d.Severity != tc.severity {
+				t.Errorf("Diagnostic = %d:%d %s, want 3:%d %s", d.Line, d.Column, d.Severity, tc.column, tc.severity)
+			}
+			if !strings.Contains(d.Message, tc.message) {
+				t.Errorf("Message = %q, want containing %q", d.Message, tc.message)
+			}
+		})
+	}
+}
+
+func TestEvents_ParamsAgainstConfig(t *testing.T) {
+	const input = `[09:00:00.000] 1 1
+[09:05:00.000] 2 1 09:30:00.000
+[09:29:00.000] 3 1
+[09:30:00.000] 4 1
+[09:45:00.000] 5 1 2
+[09:45:05.000] 6 1 6
+[09:46:00.000] 11 1
+`
+
+	diagnostics, err := Events(strings.NewReader(input), testConfig(), nil)
+	if err != nil {
+		t.Fatalf("Events() error = %v", err)
+	}
+
+	want := []struct {
+		line     int
+		severity Severity
+		message  string
+	}{
+		{line: 5, severity: SeverityError, message: "огневой рубеж 2"},
+		{line: 6, severity: SeverityError, message: "мишень 6"},
+		{line: 7, severity: SeverityWarning, message: "обязательного параметра"},
+	}
+	if len(diagnostics) != len(want) {
+		t.Fatalf("Expected %d diagnostics, got %v", len(want), diagnostics)
+	}
+	for i, w := range want {
+		d := diagnostics[i]
+		if d.Line != w.line || d.Severity != w.severity || !strings.Contains(d.Message, w.message) {
+			t.Errorf("diagnostics[%d] = %v, want line %d %s containing %q", i, d, w.line, w.severity, w.message)
+		}
+	}
+
+	if !HasErrors(diagnostics) {
+		t.Error("HasErrors() = false, want true")
+	}
+	if HasErrors(diagnostics[2:]) {
+		t.Error("HasErrors() = true for warnings only, want false")
+	}
+}
+
+func TestSplitFields(t *testing.T) {
+	got := splitFields("[09:45:05.000]  6 1 1 # Попадание")
+	want := []field{{"[09:45:05.000]", 1}, {"6", 17}, {"1", 19}, {"1", 21}}
+
+	if len(got) != len(want) {
+		t.Fatalf("splitFields() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("splitFields()[%d] = %v, want %v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestEvents_MidnightIsNotTimeGoingBack(t *testing.T) {
+	const input = `[23:50:00.000] 1 1
+[00:05:00.000] 2 1 00:30:00.000
+[00:04:00.000] 1 2
+`
+
+	diagnostics, err := Events(strings.NewReader(input), testConfig(), nil)
+	if err != nil {
+		t.Fatalf("Events() error = %v", err)
+	}
+
+	// Переход через полночь допустим, а откат на минуту после него - нет
+	if len(diagnostics) != 1 || diagnostics[0].Line != 3 {
+		t.Errorf("Expected a single diagnostic on line 3, got %v", diagnostics)
+	}
+}
+
+func TestEvents_SkippedPenaltyLoops(t *testing.T) {
+	const input = `[09:00:00.000] 1 1
+[09:05:00.000] 2 1 09:30:00.000
+[09:29:00.000] 3 1
+[09:30:00.000] 4 1
+[09:45:00.000] 5 1 1
+[09:45:06.000] 61 1 2
+[09:45:07.000] 7 1
+[10:00:00.000] 10 1
+`
+
+	diagnostics, err := Events(strings.NewReader(input), testConfig(), nil)
+	if err != nil {
+		t.Fatalf("Events() error = %v", err)
+	}
+
+	if len(diagnostics) != 1 {
+		t.Fatalf("Expected 1 diagnostic, got %v", diagnostics)
+	}
+	d := diagnostics[0]
+	if d.Line != 8 || d.Severity != SeverityWarning || !strings.Contains(d.Message, "штрафных кругов меньше") {
+		t.Errorf("Unexpected diagnostic %v", d)
+	}
+}
+
+func TestEvents_FastPenaltyLoops(t *testing.T) {
+	// Два штрафных круга по 50 м за 5 секунд - быстрее 8 м/с
+	const input = `[09:00:00.000] 1 1
+[09:05:00.000] 2 1 09:30:00.000
+[09:29:00.000] 3 1
+[09:30:00.000] 4 1
+[09:45:00.000] 5 1 1
+[09:45:05.000] 61 1 1
+[09:45:06.000] 61 1 2
+[09:45:07.000] 7 1
+[09:45:10.000] 8 1
+[09:45:12.000] 9 1
+[09:45:12.000] 8 1
+[09:45:15.000] 9 1
+[10:00:00.000] 10 1
+`
+
+	diagnostics, err := Events(strings.NewReader(input), testConfig(), nil)
+	if err != nil {
+		t.Fatalf("Events() error = %v", err)
+	}
+
+	if len(diagnostics) != 1 {
+		t.Fatalf("Expected 1 diagnostic, got %v", diagnostics)
+	}
+	d := diagnostics[0]
+	if d.Line != 13 || d.Severity != SeverityWarning || !strings.Contains(d.Message, "слишком быстро") {
+		t.Errorf("Unexpected diagnostic %v", d)
+	}
+}
+
+func TestEvents_Roster(t *testing.T) {
+	const input = `[09:00:00.000] 1 1
+[09:00:00.000] 1 2
+[09:05:00.000] 2 2 09:30:00.000
+`
+	roster := []models.Entry{{Bib: 1, Name: "Johannes Boe"}}
+
+	diagnostics, err := Events(strings.NewReader(input), testConfig(), roster)
+	if err != nil {
+		t.Fatalf("Events() error = %v", err)
+	}
+
+	// Предупреждение только при первом появлении участника
+	if len(diagnostics) != 1 {
+		t.Fatalf("Expected 1 diagnostic, got %v", diagnostics)
+	}
+	d := diagnostics[0]
+	if d.Line != 2 || d.Column != 16 || d.Severity != SeverityWarning || !strings.Contains(d.Message, "отсутствует в ростере") {
+		t.Errorf("Unexpected diagnostic %v", d)
+	}
+}
+
+func TestEvents_Relay(t *testing.T) {
+	cfg := testConfig()
+	cfg.Format = "relay"
+	cfg.Legs = 2
+	roster := []models.Entry{
+		{ID: 1, Name: "A", Team: "Norway", Leg: 1},
+		{ID: 2, Name: "B", Team: "Norway", Leg: 2},
+		{ID: 3, Name: "C", Team: "France", Leg: 1},
+		{ID: 4, Name: "D", Team: "France", Leg: 2},
+	}
+	const input = `[09:00:00.000] 1 1
+[09:00:00.000] 1 2
+[09:00:00.000] 1 4
+[09:29:00.000] 3 1
+[09:30:00.000] 4 1
+[09:40:00.000] 10 1
+[09:50:00.000] 10 1
+[09:50:00.000] 12 1 4
+[09:50:00.000] 12 1 x
+[09:50:00.000] 12 1 2
+`
+
+	if _, err := Events(strings.NewReader(input), cfg, nil); err == nil {
+		t.Error("Events() expected error for relay without roster")
+	}
+
+	diagnostics, err := Events(strings.NewReader(input), cfg, roster)
+	if err != nil {
+		t.Fatalf("Events() error = %v", err)
+	}
+	if len(diagnostics) != 2 {
+		t.Fatalf("Expected 2 diagnostics, got %v", diagnostics)
+	}
+	if d := diagnostics[0]; d.Line != 8 || !strings.Contains(d.Message, "этап 2 команды Norway бежит участник 2") {
+		t.Errorf("Unexpected diagnostic %v", d)
+	}
+	if d := diagnostics[1]; d.Line != 9 || d.Column != 21 || !strings.Contains(d.Message, "ID принимающего участника") {
+		t.Errorf("Unexpected diagnostic %v", d)
+	}
+}